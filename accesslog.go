@@ -0,0 +1,115 @@
+package refasthttp
+
+import (
+	"github.com/remicro/api/logging"
+	"github.com/remicro/api/net/rehttp"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+const defaultMaxLoggedBody = 1024
+
+// AccessLogConfig turns on one log entry per call. Headers adds the request
+// and response headers, Bodies the bodies cut to MaxBodySize bytes, 1024
+// when zero. Both go through the redaction policy of the factory. Timing
+// adds the phases of the call, see Timing.
+type AccessLogConfig struct {
+	Headers     bool
+	Bodies      bool
+	MaxBodySize int
+	Timing      bool
+}
+
+// AccessLog logs every call with its method, URL with the path template,
+// query, service, node, status, latency, body sizes, number of attempts and
+// error. Calls are logged at Info, 4xx responses at Warn, 5xx responses and
+// failures at Error.
+func (f *Factory) AccessLog(config AccessLogConfig) *Factory {
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxLoggedBody
+	}
+	f.accessLog = &config
+	return f
+}
+
+func (fhc *fastHttpClient) logAccess(started time.Time, response rehttp.Response, err error) {
+	config := fhc.factory.accessLog
+	redaction := &fhc.factory.redaction
+	status := 0
+	if response != nil {
+		status = response.Status()
+	}
+	res, _ := response.(*responseImpl)
+	var entry logging.Entry
+	switch {
+	case err != nil || status >= 500:
+		entry = fhc.logger.Error()
+	case status >= 400:
+		entry = fhc.logger.Warn()
+	default:
+		entry = fhc.logger.Info()
+	}
+	entry = entry.
+		String("method", string(fhc.req.Header.Method())).
+		String("url", string(fhc.uri.Scheme())+"://"+string(fhc.uri.Host())+fhc.pathTemplate()).
+		String("service", fhc.service).
+		String("node", fhc.node()).
+		Int("status", status).
+		Duration("latency", time.Since(started)).
+		Int("request_size", len(fhc.req.Body())).
+		Int("attempts", fhc.attempts)
+	if query := fhc.uri.QueryArgs(); query.Len() > 0 {
+		masked := fasthttp.AcquireArgs()
+		query.CopyTo(masked)
+		redaction.args(masked)
+		entry = entry.String("query", string(masked.QueryString()))
+		fasthttp.ReleaseArgs(masked)
+	}
+	if response != nil {
+		entry = entry.Int("response_size", len(response.Body()))
+	}
+	if config.Headers {
+		entry = entry.String("request_headers", redaction.headers(fhc.req.Header.VisitAll))
+		if res != nil {
+			entry = entry.String("response_headers", redaction.headers(res.response.Header.VisitAll))
+		}
+	}
+	if config.Bodies {
+		body := redaction.body(fhc.req.Header.ContentType(), fhc.req.Body())
+		entry = entry.String("request_body", truncateBody(body, config.MaxBodySize))
+		if res != nil {
+			body = redaction.body(res.response.Header.ContentType(), res.response.Body())
+			entry = entry.String("response_body", truncateBody(body, config.MaxBodySize))
+		}
+	}
+	if config.Timing {
+		entry = entry.
+			Duration("queue", fhc.timing.Queue).
+			Duration("dns", fhc.timing.DNS).
+			Duration("connect", fhc.timing.Connect).
+			Duration("tls", fhc.timing.TLS).
+			Duration("first_byte", fhc.timing.FirstByte).
+			Duration("body_read", fhc.timing.BodyRead).
+			Duration("decode", fhc.timing.Decode).
+			Bool("reused", fhc.timing.Reused)
+	}
+	if err != nil {
+		entry = entry.Err(err)
+	}
+	entry.Log("http call")
+}
+
+// node is the host the request was sent to, or the unix socket path.
+func (fhc *fastHttpClient) node() string {
+	if fhc.socket != "" {
+		return unixScheme + fhc.socket
+	}
+	return string(fhc.uri.Host())
+}
+
+func truncateBody(body []byte, max int) string {
+	if len(body) <= max {
+		return string(body)
+	}
+	return string(body[:max]) + "...(truncated)"
+}
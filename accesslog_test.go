@@ -0,0 +1,86 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFactory_AccessLog(t *testing.T) {
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		status, err := strconv.Atoi(string(ctx.QueryArgs().Peek("status")))
+		if err == nil {
+			ctx.SetStatusCode(status)
+		}
+		ctx.Write(ctx.Path())
+	})
+	defer fx.Finish()
+	bln := reFastHttpFixture.Balancer(map[string][]string{"users": {fx.Address()}})
+
+	t.Run("expect one entry per call with path template", func(t *testing.T) {
+		logger := &recordLogger{}
+		factory := NewFactory().Logger(logger).Balancer(bln).AccessLog(AccessLogConfig{})
+		res, err := factory.Service("users").
+			GET("/users/{id}").(Builder).
+			PathParam("id", "42").
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, "/users/42", string(res.Body()))
+
+		entries := logger.Entries()
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		assert.Equal(t, "info", entry.Level)
+		assert.Equal(t, "http call", entry.Message)
+		assert.Equal(t, "GET", entry.Fields["method"])
+		assert.Equal(t, "http://"+fixtureHost(fx)+"/users/{id}", entry.Fields["url"])
+		assert.Equal(t, "users", entry.Fields["service"])
+		assert.Equal(t, fixtureHost(fx), entry.Fields["node"])
+		assert.Equal(t, fasthttp.StatusOK, entry.Fields["status"])
+		assert.Equal(t, 0, entry.Fields["request_size"])
+		assert.Equal(t, len("/users/42"), entry.Fields["response_size"])
+		assert.Equal(t, 1, entry.Fields["attempts"])
+		assert.True(t, entry.Fields["latency"].(time.Duration) > 0)
+		assert.NotContains(t, entry.Fields, "error")
+		assert.NotContains(t, entry.Fields, "response_body")
+	})
+
+	t.Run("expect level by outcome", func(t *testing.T) {
+		logger := &recordLogger{}
+		factory := NewFactory().Logger(logger).AccessLog(AccessLogConfig{})
+		for _, status := range []string{"201", "404", "503"} {
+			_, err := factory.To(fx.Address()).GET("/").QueryParam("status", status).Go()
+			require.NoError(t, err)
+		}
+		_, err := factory.To(closedAddress(t)).GET("/").Go()
+		require.Error(t, err)
+
+		var levels []string
+		for _, entry := range logger.Entries() {
+			levels = append(levels, entry.Level)
+		}
+		assert.Equal(t, []string{"info", "warn", "error", "error"}, levels)
+		assert.Equal(t, err, logger.Entries()[3].Fields["error"])
+	})
+
+	t.Run("expect truncated bodies", func(t *testing.T) {
+		logger := &recordLogger{}
+		factory := NewFactory().Logger(logger).AccessLog(AccessLogConfig{Bodies: true, MaxBodySize: 4})
+		_, err := factory.To(fx.Address()).
+			POST("/response").
+			Encoder(reFastHttpFixture.Encoder()).
+			ToEncode(Object{Label: "label"}).
+			Go()
+		require.NoError(t, err)
+
+		entry := logger.Entries()[0]
+		assert.Equal(t, `{"la...(truncated)`, entry.Fields["request_body"])
+		assert.Equal(t, "/res...(truncated)", entry.Fields["response_body"])
+		assert.True(t, strings.HasSuffix(entry.Fields["url"].(string), "/response"))
+	})
+}
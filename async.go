@@ -0,0 +1,153 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"github.com/remicro/api/net/rehttp"
+	"sync"
+)
+
+const (
+	defaultAsyncWorkers = 64
+	defaultAsyncQueue   = 1024
+)
+
+var (
+	ErrCanceled = errors.New("request canceled")
+)
+
+// Future is the pending result of GoAsync.
+type Future struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	once     sync.Once
+	response rehttp.Response
+	err      error
+}
+
+func newFuture(cancel context.CancelFunc) *Future {
+	return &Future{done: make(chan struct{}), cancel: cancel}
+}
+
+// Wait blocks until the request finished or the future was canceled. Called
+// from a worker of the pool, such as in a GoCallback callback, it needs
+// another worker to be free or the request never starts. Chain requests
+// with GoCallback there instead.
+func (fut *Future) Wait() (response rehttp.Response, err error) {
+	<-fut.done
+	return fut.response, fut.err
+}
+
+// Done is closed once Wait doesn't block anymore.
+func (fut *Future) Done() <-chan struct{} {
+	return fut.done
+}
+
+// Cancel makes Wait return ErrCanceled. A request still queued is never
+// sent, one already running gets its context canceled, which ends its
+// waits for rate limits, bulkheads and coalesced requests. fasthttp can't
+// abort a round trip in flight, its result is dropped.
+func (fut *Future) Cancel() {
+	fut.finish(nil, ErrCanceled)
+	fut.cancel()
+}
+
+func (fut *Future) finish(response rehttp.Response, err error) {
+	fut.once.Do(func() {
+		fut.response = response
+		fut.err = err
+		close(fut.done)
+	})
+}
+
+func (fut *Future) canceled() bool {
+	select {
+	case <-fut.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (fhc *fastHttpClient) GoAsync() *Future {
+	parent := fhc.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	fhc.ctx = ctx
+	fut := newFuture(cancel)
+	fhc.factory.async.submit(func() {
+		defer cancel()
+		if fut.canceled() {
+			return
+		}
+		fut.finish(fhc.Go())
+	})
+	return fut
+}
+
+// GoCallback sends the request on the worker pool of the factory and calls
+// callback from the worker with the result. When the queue is full the
+// request runs on the calling goroutine instead, which blocks the caller
+// but lets callbacks send requests themselves without deadlocking the pool.
+func (fhc *fastHttpClient) GoCallback(callback func(response rehttp.Response, err error)) {
+	task := func() {
+		callback(fhc.Go())
+	}
+	if !fhc.factory.async.trySubmit(task) {
+		task()
+	}
+}
+
+// Async bounds the requests sent with GoAsync and GoCallback to workers
+// concurrent ones, up to queue more wait for a worker and further calls
+// block until there is room. Defaults are 64 workers and 1024 queued
+// requests.
+func (f *Factory) Async(workers, queue int) *Factory {
+	f.async.workers = workers
+	f.async.queue = queue
+	return f
+}
+
+// asyncPool starts its workers with the first submitted task.
+type asyncPool struct {
+	once    sync.Once
+	workers int
+	queue   int
+	tasks   chan func()
+}
+
+func (ap *asyncPool) submit(task func()) {
+	ap.once.Do(ap.start)
+	ap.tasks <- task
+}
+
+func (ap *asyncPool) trySubmit(task func()) bool {
+	ap.once.Do(ap.start)
+	select {
+	case ap.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+func (ap *asyncPool) start() {
+	if ap.workers < 1 {
+		ap.workers = 1
+	}
+	if ap.queue < 0 {
+		ap.queue = 0
+	}
+	ap.tasks = make(chan func(), ap.queue)
+	for i := 0; i < ap.workers; i++ {
+		go ap.work()
+	}
+}
+
+func (ap *asyncPool) work() {
+	for task := range ap.tasks {
+		task()
+	}
+}
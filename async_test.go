@@ -0,0 +1,198 @@
+package refasthttp
+
+import (
+	"github.com/remicro/api/net/rehttp"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFastHttpClient_GoAsync(t *testing.T) {
+	t.Run("expect response from future", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString("OK")
+		})
+		defer fx.Finish()
+
+		fut := NewFactory().To(fx.Address()).GET("/").(Builder).GoAsync()
+		select {
+		case <-fut.Done():
+		case <-time.After(time.Second):
+			t.Fatal("future not done")
+		}
+		res, err := fut.Wait()
+		require.NoError(t, err)
+		assert.Equal(t, "OK", string(res.Body()))
+	})
+
+	t.Run("expect callback with response", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.SetStatusCode(fasthttp.StatusAccepted)
+		})
+		defer fx.Finish()
+
+		results := make(chan int, 1)
+		NewFactory().To(fx.Address()).GET("/").(Builder).GoCallback(func(response rehttp.Response, err error) {
+			assert.NoError(t, err)
+			results <- response.Status()
+		})
+		assert.Equal(t, fasthttp.StatusAccepted, <-results)
+	})
+
+	t.Run("expect workers to bound concurrency and cancel to skip queued", func(t *testing.T) {
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var running, maxRunning, served int
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			mu.Lock()
+			running++
+			served++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+		defer fx.Finish()
+
+		factory := NewFactory().Async(2, 10)
+		var futures []*Future
+		for i := 0; i < 6; i++ {
+			futures = append(futures, factory.To(fx.Address()).GET("/").(Builder).GoAsync())
+		}
+		eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return running == 2
+		})
+		canceled := futures[5]
+		canceled.Cancel()
+		_, err := canceled.Wait()
+		assert.Equal(t, ErrCanceled, err)
+
+		close(release)
+		for _, fut := range futures[:5] {
+			_, err := fut.Wait()
+			assert.NoError(t, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, 2, maxRunning)
+		assert.Equal(t, 5, served)
+	})
+
+	t.Run("expect cancel to stop a running request waiting for a rate limit", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {})
+		defer fx.Finish()
+
+		factory := NewFactory().Async(1, 10).RateLimit(fixtureHost(fx), RateLimit{Rate: 0.1, Burst: 1, Wait: true})
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		waiting := factory.To(fx.Address()).GET("/").(Builder).GoAsync()
+		time.Sleep(20 * time.Millisecond)
+		waiting.Cancel()
+		_, err = waiting.Wait()
+		assert.Equal(t, ErrCanceled, err)
+		freed := make(chan struct{})
+		factory.async.submit(func() {
+			close(freed)
+		})
+		select {
+		case <-freed:
+		case <-time.After(time.Second):
+			t.Fatal("worker still waiting for the rate limit")
+		}
+	})
+
+	t.Run("expect callback chaining not to deadlock a full pool", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString("OK")
+		})
+		defer fx.Finish()
+
+		factory := NewFactory().Async(1, 0)
+		results := make(chan string, 1)
+		factory.To(fx.Address()).GET("/").(Builder).GoCallback(func(response rehttp.Response, err error) {
+			assert.NoError(t, err)
+			factory.To(fx.Address()).GET("/").(Builder).GoCallback(func(response rehttp.Response, err error) {
+				assert.NoError(t, err)
+				results <- string(response.Body())
+			})
+		})
+		select {
+		case body := <-results:
+			assert.Equal(t, "OK", body)
+		case <-time.After(time.Second):
+			t.Fatal("nested callback deadlocked")
+		}
+	})
+
+	t.Run("expect waiting in a callback to need a free worker", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString("OK")
+		})
+		defer fx.Finish()
+
+		results := make(chan error, 1)
+		factory := NewFactory().Async(2, 4)
+		factory.To(fx.Address()).GET("/").(Builder).GoCallback(func(response rehttp.Response, err error) {
+			_, err = factory.To(fx.Address()).GET("/").(Builder).GoAsync().Wait()
+			results <- err
+		})
+		select {
+		case err := <-results:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("wait in callback blocked with a free worker")
+		}
+
+		inner := make(chan *Future, 1)
+		factory = NewFactory().Async(1, 4)
+		factory.To(fx.Address()).GET("/").(Builder).GoCallback(func(response rehttp.Response, err error) {
+			fut := factory.To(fx.Address()).GET("/").(Builder).GoAsync()
+			inner <- fut
+			_, err = fut.Wait()
+			results <- err
+		})
+		fut := <-inner
+		select {
+		case <-results:
+			t.Fatal("request queued behind its only worker was sent")
+		case <-time.After(50 * time.Millisecond):
+		}
+		fut.Cancel()
+		assert.Equal(t, ErrCanceled, <-results)
+	})
+
+	t.Run("expect callback on the caller when the queue is full", func(t *testing.T) {
+		release := make(chan struct{})
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			if ctx.QueryArgs().Has("hold") {
+				<-release
+			}
+		})
+		defer fx.Finish()
+		defer close(release)
+
+		factory := NewFactory().Async(1, 0)
+		held := factory.To(fx.Address()).GET("/").QueryParam("hold", "1").(Builder).GoAsync()
+		eventually(t, func() bool {
+			return factory.Stats()[fixtureHost(fx)].InUse == 1
+		})
+		called := false
+		factory.To(fx.Address()).GET("/").(Builder).GoCallback(func(response rehttp.Response, err error) {
+			assert.NoError(t, err)
+			called = true
+		})
+		assert.True(t, called)
+		assert.False(t, held.canceled())
+	})
+}
@@ -0,0 +1,132 @@
+package refasthttp
+
+import (
+	"github.com/remicro/api/net/rehttp"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+// BatchResult is the outcome of a single request of a Batch.
+type BatchResult struct {
+	Response rehttp.Response
+	Err      error
+}
+
+// Batch sends prepared builders concurrently and gathers their results in
+// the order the builders were added.
+type Batch struct {
+	builders []rehttp.Builder
+	limit    int
+	failFast bool
+	deadline time.Time
+}
+
+func NewBatch(builders ...rehttp.Builder) *Batch {
+	return &Batch{builders: builders}
+}
+
+func (b *Batch) Add(builders ...rehttp.Builder) *Batch {
+	b.builders = append(b.builders, builders...)
+	return b
+}
+
+// Concurrency limits how many requests are in flight at once, zero means
+// all of them.
+func (b *Batch) Concurrency(limit int) *Batch {
+	b.limit = limit
+	return b
+}
+
+// FailFast makes Go return with the first failed request. Requests not
+// finished by then get ErrCanceled, the ones in flight are left to finish in
+// the background.
+func (b *Batch) FailFast(failFast bool) *Batch {
+	b.failFast = failFast
+	return b
+}
+
+// Deadline is shared by every request of the batch, requests which haven't
+// finished in time get fasthttp.ErrTimeout.
+func (b *Batch) Deadline(deadline time.Time) *Batch {
+	b.deadline = deadline
+	return b
+}
+
+type batchDone struct {
+	index  int
+	result BatchResult
+}
+
+// Go sends the requests and returns one result per builder. err is the
+// failure which stopped a fail fast batch, nil otherwise.
+func (b *Batch) Go() (results []BatchResult, err error) {
+	n := len(b.builders)
+	results = make([]BatchResult, n)
+	limit := b.limit
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	var timeout <-chan time.Time
+	if !b.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(b.deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	done := make(chan batchDone, n)
+	received := make([]bool, n)
+	next, running := 0, 0
+	start := func() {
+		index, builder := next, b.builders[next]
+		next++
+		running++
+		b.bindDeadline(builder)
+		go func() {
+			response, reqErr := builder.Go()
+			done <- batchDone{index: index, result: BatchResult{Response: response, Err: reqErr}}
+		}()
+	}
+	abort := func(reason error) {
+		for i := range results {
+			if !received[i] {
+				results[i].Err = reason
+			}
+		}
+	}
+
+	for next < limit {
+		start()
+	}
+	for running > 0 {
+		select {
+		case d := <-done:
+			running--
+			received[d.index] = true
+			results[d.index] = d.result
+			if d.result.Err != nil && b.failFast {
+				abort(ErrCanceled)
+				return results, d.result.Err
+			}
+			if next < n {
+				start()
+			}
+		case <-timeout:
+			abort(fasthttp.ErrTimeout)
+			if b.failFast {
+				err = fasthttp.ErrTimeout
+			}
+			return
+		}
+	}
+	return
+}
+
+// bindDeadline makes the transport give up on the request at the batch
+// deadline, other builders are only abandoned.
+func (b *Batch) bindDeadline(builder rehttp.Builder) {
+	fhc, ok := builder.(*fastHttpClient)
+	if !ok || b.deadline.IsZero() {
+		return
+	}
+	fhc.bindDeadline(b.deadline)
+}
@@ -0,0 +1,100 @@
+package refasthttp
+
+import (
+	"github.com/remicro/api/net/rehttp"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func closedAddress(t *testing.T) string {
+	l, err := net.Listen("tcp4", "localhost:0")
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+	return "http://" + l.Addr().String()
+}
+
+func TestBatch(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	release := make(chan struct{})
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		if string(ctx.Path()) == "/slow" {
+			<-release
+		} else {
+			time.Sleep(10 * time.Millisecond)
+		}
+		mu.Lock()
+		running--
+		mu.Unlock()
+		ctx.Write(ctx.Path())
+	})
+	defer fx.Finish()
+	defer close(release)
+
+	t.Run("expect ordered results with per request errors", func(t *testing.T) {
+		mu.Lock()
+		maxRunning = 0
+		mu.Unlock()
+		factory := NewFactory()
+		results, err := NewBatch(
+			factory.To(fx.Address()).GET("/a"),
+			factory.To(closedAddress(t)).GET("/"),
+		).Add(
+			factory.To(fx.Address()).GET("/b"),
+			factory.To(fx.Address()).GET("/c"),
+			factory.To(fx.Address()).GET("/d"),
+		).Concurrency(2).Go()
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+		for i, path := range map[int]string{0: "/a", 2: "/b", 3: "/c", 4: "/d"} {
+			require.NoError(t, results[i].Err)
+			assert.Equal(t, path, string(results[i].Response.Body()))
+		}
+		assert.Error(t, results[1].Err)
+		assert.Nil(t, results[1].Response)
+		mu.Lock()
+		assert.True(t, maxRunning <= 2)
+		mu.Unlock()
+	})
+
+	t.Run("expect fail fast to cancel the rest", func(t *testing.T) {
+		factory := NewFactory()
+		var builders []rehttp.Builder
+		for i := 0; i < 3; i++ {
+			builders = append(builders, factory.To(fx.Address()).GET("/slow"))
+		}
+		builders = append(builders, factory.To(closedAddress(t)).GET("/"))
+		results, err := NewBatch(builders...).FailFast(true).Go()
+		require.Error(t, err)
+		assert.Equal(t, err, results[3].Err)
+		for _, result := range results[:3] {
+			assert.Equal(t, ErrCanceled, result.Err)
+		}
+	})
+
+	t.Run("expect shared deadline", func(t *testing.T) {
+		factory := NewFactory()
+		started := time.Now()
+		results, err := NewBatch(
+			factory.To(fx.Address()).GET("/slow"),
+			factory.To(fx.Address()).GET("/slow"),
+		).Deadline(time.Now().Add(50 * time.Millisecond)).Go()
+		require.NoError(t, err)
+		assert.True(t, time.Since(started) < time.Second)
+		for _, result := range results {
+			assert.Equal(t, fasthttp.ErrTimeout, result.Err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrBulkheadFull = errors.New("bulkhead full")
+)
+
+// BulkheadConfig caps the concurrent requests to a service. Up to MaxQueue
+// requests beyond MaxInFlight wait for QueueTimeout, zero waits until the
+// context of the builder is done.
+type BulkheadConfig struct {
+	MaxInFlight  int
+	MaxQueue     int
+	QueueTimeout time.Duration
+}
+
+// BulkheadRejectedError is returned when the queue of Service is full or a
+// queued request timed out. It matches ErrBulkheadFull.
+type BulkheadRejectedError struct {
+	Service string
+	Waited  time.Duration
+}
+
+func (e *BulkheadRejectedError) Error() string {
+	if e.Waited > 0 {
+		return fmt.Sprintf("%s: %s after waiting %s", e.Service, ErrBulkheadFull, e.Waited)
+	}
+	return fmt.Sprintf("%s: %s", e.Service, ErrBulkheadFull)
+}
+
+func (e *BulkheadRejectedError) Is(target error) bool {
+	return target == ErrBulkheadFull
+}
+
+// BulkheadStats reports the utilization of the bulkhead of a service.
+type BulkheadStats struct {
+	InFlight    int
+	Queued      int
+	MaxInFlight int
+	MaxQueue    int
+}
+
+// Bulkhead isolates requests made with Service(service) from the rest: Go
+// blocks while MaxInFlight of them are running and fails with a
+// *BulkheadRejectedError when it can't get a slot.
+func (f *Factory) Bulkhead(service string, config BulkheadConfig) *Factory {
+	if config.MaxInFlight < 1 {
+		config.MaxInFlight = 1
+	}
+	f.bulkheads.mu.Lock()
+	defer f.bulkheads.mu.Unlock()
+	if f.bulkheads.services == nil {
+		f.bulkheads.services = make(map[string]*bulkhead)
+	}
+	f.bulkheads.services[service] = &bulkhead{
+		service: service,
+		config:  config,
+		slots:   make(chan struct{}, config.MaxInFlight),
+	}
+	return f
+}
+
+func (f *Factory) BulkheadStats() map[string]BulkheadStats {
+	f.bulkheads.mu.Lock()
+	defer f.bulkheads.mu.Unlock()
+	stats := make(map[string]BulkheadStats, len(f.bulkheads.services))
+	for service, bh := range f.bulkheads.services {
+		bh.mu.Lock()
+		stats[service] = BulkheadStats{
+			InFlight:    len(bh.slots),
+			Queued:      bh.queued,
+			MaxInFlight: bh.config.MaxInFlight,
+			MaxQueue:    bh.config.MaxQueue,
+		}
+		bh.mu.Unlock()
+	}
+	return stats
+}
+
+type bulkheads struct {
+	mu       sync.Mutex
+	services map[string]*bulkhead
+}
+
+func (b *bulkheads) find(service string) *bulkhead {
+	if service == "" {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.services[service]
+}
+
+type bulkhead struct {
+	service string
+	config  BulkheadConfig
+	slots   chan struct{}
+	mu      sync.Mutex
+	queued  int
+}
+
+func (bh *bulkhead) acquire(ctx context.Context) (err error) {
+	select {
+	case bh.slots <- struct{}{}:
+		return
+	default:
+	}
+	bh.mu.Lock()
+	if bh.queued >= bh.config.MaxQueue {
+		bh.mu.Unlock()
+		return &BulkheadRejectedError{Service: bh.service}
+	}
+	bh.queued++
+	bh.mu.Unlock()
+	defer func() {
+		bh.mu.Lock()
+		bh.queued--
+		bh.mu.Unlock()
+	}()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var timeout <-chan time.Time
+	if bh.config.QueueTimeout > 0 {
+		timer := time.NewTimer(bh.config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case bh.slots <- struct{}{}:
+		return
+	case <-timeout:
+		return &BulkheadRejectedError{Service: bh.service, Waited: bh.config.QueueTimeout}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (bh *bulkhead) release() {
+	<-bh.slots
+}
+
+// bulkheadLease releases the slot of a call once the call and the attempts
+// it left running are done.
+type bulkheadLease struct {
+	bh   *bulkhead
+	refs int32
+}
+
+func (l *bulkheadLease) retain() {
+	if l != nil {
+		atomic.AddInt32(&l.refs, 1)
+	}
+}
+
+func (l *bulkheadLease) release() {
+	if l != nil && atomic.AddInt32(&l.refs, -1) == 0 {
+		l.bh.release()
+	}
+}
@@ -0,0 +1,74 @@
+package refasthttp
+
+import (
+	"errors"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+	"time"
+)
+
+func TestFactory_Bulkhead(t *testing.T) {
+	release := make(chan struct{})
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == "/slow" {
+			<-release
+		}
+	})
+	defer fx.Finish()
+	bln := reFastHttpFixture.Balancer(map[string][]string{"service": {fx.Address()}})
+	factory := NewFactory().Balancer(bln).Bulkhead("service", BulkheadConfig{
+		MaxInFlight:  1,
+		MaxQueue:     1,
+		QueueTimeout: 30 * time.Millisecond,
+	})
+	stats := func() BulkheadStats {
+		return factory.BulkheadStats()["service"]
+	}
+
+	running := make(chan error)
+	go func() {
+		_, err := factory.Service("service").GET("/slow").Go()
+		running <- err
+	}()
+	eventually(t, func() bool {
+		return stats().InFlight == 1
+	})
+
+	queued := make(chan error)
+	go func() {
+		_, err := factory.Service("service").GET("/").Go()
+		queued <- err
+	}()
+	eventually(t, func() bool {
+		return stats().Queued == 1
+	})
+	assert.Equal(t, BulkheadStats{InFlight: 1, Queued: 1, MaxInFlight: 1, MaxQueue: 1}, stats())
+
+	t.Run("expect rejection when queue is full", func(t *testing.T) {
+		_, err := factory.Service("service").GET("/").Go()
+		assert.True(t, errors.Is(err, ErrBulkheadFull))
+		var rejected *BulkheadRejectedError
+		require.True(t, errors.As(err, &rejected))
+		assert.Equal(t, "service", rejected.Service)
+		assert.Equal(t, time.Duration(0), rejected.Waited)
+	})
+
+	t.Run("expect other requests not to be limited", func(t *testing.T) {
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		assert.NoError(t, err)
+	})
+
+	t.Run("expect rejection after queue timeout", func(t *testing.T) {
+		err := <-queued
+		var rejected *BulkheadRejectedError
+		require.True(t, errors.As(err, &rejected))
+		assert.Equal(t, 30*time.Millisecond, rejected.Waited)
+	})
+
+	close(release)
+	require.NoError(t, <-running)
+	assert.Equal(t, BulkheadStats{MaxInFlight: 1, MaxQueue: 1}, stats())
+}
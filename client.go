@@ -1,19 +1,39 @@
 package refasthttp
 
 import (
+	"bytes"
+	"context"
 	"github.com/remicro/api/cloud/balancer"
 	"github.com/remicro/api/logging"
 	"github.com/remicro/api/net/rehttp"
 	"github.com/remicro/api/serialization"
 	"github.com/valyala/fasthttp"
+	"net/url"
+	"time"
 )
 
-func New() rehttp.Builder {
-	return &fastHttpClient{
-		req:    fasthttp.AcquireRequest(),
-		uri:    fasthttp.AcquireURI(),
-		logger: dummyLogger{},
-	}
+type Builder interface {
+	rehttp.Builder
+	AddQueryParam(key, value string) Builder
+	QueryParams(values url.Values) Builder
+	QueryStruct(object interface{}) Builder
+	Redirects(policy RedirectPolicy) Builder
+	Proxy(proxyURL string) Builder
+	Context(ctx context.Context) Builder
+	Idempotent(key ...string) Builder
+	PathParam(key, value string) Builder
+	// GoAsync queues the request on the worker pool of the factory. Waiting
+	// for the future from a GoCallback callback takes a second worker, with
+	// every worker waiting like that the pool deadlocks.
+	GoAsync() *Future
+	// GoCallback queues the request and calls callback from a worker. When
+	// the queue is full the request and callback run on the calling
+	// goroutine, GoCallback then blocks until both are done.
+	GoCallback(callback func(response rehttp.Response, err error))
+}
+
+func New() Builder {
+	return defaultFactory.New()
 }
 
 type fastHttpClient struct {
@@ -28,7 +48,23 @@ type fastHttpClient struct {
 	encObj     interface{}
 	decObj     interface{}
 	uri        *fasthttp.URI
-	bln balancer.Balancer
+	query      *fasthttp.Args
+	querySet   map[string]bool
+	bln        balancer.Balancer
+	factory    *Factory
+	redirect   *RedirectPolicy
+	proxy      *string
+	socket     string
+	service    string
+	lease      *bulkheadLease
+	deadline   time.Time
+	ctx        context.Context
+	traceCtx   context.Context
+	pathParams map[string]string
+	template   string
+	attempts   int
+	timing     Timing
+	err        error
 }
 
 func (fhc *fastHttpClient) Balancer(bln balancer.Balancer) rehttp.Builder {
@@ -41,7 +77,11 @@ func (fhc *fastHttpClient) Service(name string) rehttp.Builder {
 	if err != nil {
 		return fhc
 	}
-	fhc.uri.Parse(nil, []byte(node.Address()))
+	fhc.setAddress(node.Address())
+	fhc.service = name
+	if pinning := fhc.factory.pinning; pinning != nil {
+		pinning.bindService(node.Address(), name)
+	}
 	return fhc
 }
 
@@ -56,10 +96,21 @@ func (fhc *fastHttpClient) DecodeType(contentType rehttp.ContentType) rehttp.Bui
 }
 
 func (fhc *fastHttpClient) Address(address string) rehttp.Builder {
-	fhc.uri.Parse(nil, []byte(address))
+	fhc.setAddress(address)
+	fhc.service = ""
 	return fhc
 }
 
+func (fhc *fastHttpClient) setAddress(address string) {
+	if path, ok := parseUnixAddress(address); ok {
+		fhc.socket = path
+		fhc.uri.Parse(nil, []byte("http://localhost/"))
+		return
+	}
+	fhc.socket = ""
+	fhc.uri.Parse(nil, []byte(address))
+}
+
 func (fhc *fastHttpClient) PUT(url string) rehttp.Builder {
 	fhc.uri.SetPath(url)
 	fhc.req.Header.SetMethod("PUT")
@@ -115,8 +166,15 @@ func (fhc *fastHttpClient) OPTIONS(u string) rehttp.Builder {
 	return fhc
 }
 
+// QueryParam sets key to value, replacing the values of the address and
+// the ones added before.
 func (fhc *fastHttpClient) QueryParam(key, value string) rehttp.Builder {
-	fhc.uri.QueryArgs().Set(key, value)
+	fhc.query.Del(key)
+	fhc.query.Add(key, value)
+	if fhc.querySet == nil {
+		fhc.querySet = make(map[string]bool)
+	}
+	fhc.querySet[key] = true
 	return fhc
 }
 
@@ -139,9 +197,60 @@ func (fhc *fastHttpClient) Before(before rehttp.Before) rehttp.Builder {
 	return fhc
 }
 
+// Context bounds the request by the deadline of ctx and stops waits for a
+// rate limit when ctx is done.
+func (fhc *fastHttpClient) Context(ctx context.Context) Builder {
+	fhc.ctx = ctx
+	return fhc
+}
+
+// bindDeadline moves the deadline of the request up to deadline.
+func (fhc *fastHttpClient) bindDeadline(deadline time.Time) {
+	if fhc.deadline.IsZero() || deadline.Before(fhc.deadline) {
+		fhc.deadline = deadline
+	}
+}
+
 func (fhc *fastHttpClient) Go() (response rehttp.Response, err error) {
+	started := time.Now()
+	if metrics := fhc.factory.metrics; metrics != nil {
+		labels := fhc.callLabels()
+		metrics.Started(labels)
+		defer func() {
+			fhc.recordCall(metrics, labels, started, response, err)
+		}()
+	}
+	if fhc.factory.accessLog != nil {
+		defer func() {
+			fhc.logAccess(started, response, err)
+		}()
+	}
+	if tracer := fhc.factory.tracer; tracer != nil {
+		span := fhc.startCall(tracer)
+		defer func() {
+			fhc.endCall(span, response, err)
+		}()
+	}
+	if fhc.err != nil {
+		err = fhc.err
+		return
+	}
+	if fhc.ctx != nil {
+		if err = fhc.ctx.Err(); err != nil {
+			return
+		}
+		if deadline, ok := fhc.ctx.Deadline(); ok {
+			fhc.bindDeadline(deadline)
+		}
+	}
+	if bh := fhc.factory.bulkheads.find(fhc.service); bh != nil {
+		if err = bh.acquire(fhc.ctx); err != nil {
+			return
+		}
+		fhc.lease = &bulkheadLease{bh: bh, refs: 1}
+		defer fhc.lease.release()
+	}
 	resp := fasthttp.AcquireResponse()
-	client := &fasthttp.Client{}
 	if fhc.encObj != nil && fhc.encoder != nil {
 		var data []byte
 		data, err = fhc.encoder.Encode(fhc.encObj)
@@ -156,20 +265,35 @@ func (fhc *fastHttpClient) Go() (response rehttp.Response, err error) {
 		fhc.req.Header.Add("Accept", fhc.decodeType.String())
 	}
 
-	fhc.req.SetRequestURIBytes(fhc.uri.FullURI())
+	fhc.expandPath()
+	fhc.mergeQuery()
 	if fhc.before != nil {
 		fhc.before(fhc, string(fhc.uri.FullURI()), fhc.req.Body())
 	}
-	err = client.Do(fhc.req, resp)
+	res := &responseImpl{
+		response: resp,
+	}
+	defer func() {
+		fhc.timing.Total = time.Since(started)
+		res.timing = fhc.timing
+	}()
+	err = fhc.send(resp)
 	if err != nil {
 		return
 	}
-	response = &responseImpl{
-		response: resp,
+	if fhc.redirect != nil {
+		err = fhc.followRedirects(res)
+		if err != nil {
+			response = res
+			return
+		}
 	}
+	response = res
 
 	if fhc.decObj != nil && fhc.decoder != nil && string(resp.Header.ContentType()) == fhc.decodeType.String() {
+		decoding := time.Now()
 		err = fhc.decoder.Decode(fhc.decObj, resp.Body())
+		fhc.timing.Decode = time.Since(decoding)
 		if err != nil {
 			fhc.logger.Debug().
 				Int("status", response.Status()).
@@ -179,3 +303,83 @@ func (fhc *fastHttpClient) Go() (response rehttp.Response, err error) {
 	}
 	return
 }
+
+// send coalesces and hedges the request when the factory enables it.
+func (fhc *fastHttpClient) send(resp *fasthttp.Response) (err error) {
+	if c := fhc.factory.coalescing; c != nil && fhc.req.Header.IsGet() {
+		return c.do(fhc.ctx, fhc.deadline, fhc.coalesceKey(c.headers), resp, fhc.roundTrip)
+	}
+	return fhc.roundTrip(resp)
+}
+
+func (fhc *fastHttpClient) roundTrip(resp *fasthttp.Response) (err error) {
+	if fhc.hedgeable() {
+		return fhc.doHedged(resp)
+	}
+	return fhc.do(resp)
+}
+
+func (fhc *fastHttpClient) do(resp *fasthttp.Response) (err error) {
+	if fhc.socket != "" && len(fhc.req.Header.Host()) > 0 {
+		fhc.uri.SetHostBytes(fhc.req.Header.Host())
+	}
+	fhc.req.SetRequestURIBytes(fhc.uri.FullURI())
+	if jar := fhc.factory.jar; jar != nil {
+		jar.attach(fhc.uri, fhc.req)
+	}
+	client, err := fhc.factory.transportFor(fhc)
+	if err != nil {
+		return
+	}
+	bucket, err := fhc.throttle()
+	if err != nil {
+		return
+	}
+	throttling := fhc.factory.throttling
+	if throttling != nil && !throttling.admit(fhc.limitKey()) {
+		return ErrThrottled
+	}
+	fhc.attempts++
+	if tracer := fhc.factory.tracer; tracer != nil {
+		span := fhc.startAttempt(tracer)
+		defer func() {
+			endAttempt(span, resp, err)
+		}()
+	}
+	host := fhc.poolHost()
+	fhc.factory.pool.update(host, 0, 1)
+	sent := time.Now()
+	if fhc.deadline.IsZero() {
+		err = client.Do(fhc.req, resp)
+	} else {
+		err = client.DoDeadline(fhc.req, resp, fhc.deadline)
+	}
+	if _, pipelined := client.(*fasthttp.PipelineClient); !pipelined {
+		https := bytes.EqualFold(fhc.uri.Scheme(), []byte("https"))
+		fhc.timing.observe(&fhc.factory.pool, sent, https, resp)
+	}
+	fhc.factory.pool.update(host, 0, -1)
+	if throttling != nil {
+		if err != nil {
+			throttling.forget(fhc.limitKey())
+		} else {
+			throttling.record(fhc.limitKey(), !overloaded(resp.StatusCode()))
+		}
+	}
+	switch err {
+	case fasthttp.ErrNoFreeConns:
+		err = &PoolExhaustedError{Host: host, Wait: fhc.factory.client.MaxConnWaitTimeout, cause: err}
+	case fasthttp.ErrPipelineOverflow:
+		err = &PoolExhaustedError{Host: host, cause: err}
+	}
+	if err != nil {
+		return
+	}
+	if jar := fhc.factory.jar; jar != nil {
+		jar.store(fhc.uri, resp)
+	}
+	if bucket != nil {
+		bucket.observe(resp)
+	}
+	return
+}
@@ -0,0 +1,114 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errCoalescedPanic = errors.New("coalesced request panicked")
+
+// credentialHeaders always tell coalesced requests apart, callers must not
+// get responses meant for other credentials.
+var credentialHeaders = []string{
+	fasthttp.HeaderAuthorization,
+	fasthttp.HeaderProxyAuthorization,
+	fasthttp.HeaderCookie,
+}
+
+// Coalesce makes concurrent identical GET requests of the factory share a
+// single round trip, every caller gets its own copy of the response and
+// decodes it into its own ToDecode target. Requests are identical when the
+// full URI, the credential headers and the values of headers match.
+func (f *Factory) Coalesce(headers ...string) *Factory {
+	f.coalescing = &coalescing{
+		headers: append(append([]string(nil), credentialHeaders...), headers...),
+		calls:   make(map[string]*coalescedCall),
+	}
+	return f
+}
+
+type coalescing struct {
+	headers []string
+	mu      sync.Mutex
+	calls   map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	resp *fasthttp.Response
+	err  error
+}
+
+// do sends the request with send unless an identical one is in flight, in
+// which case its response is copied into resp. Waiting for it stops when
+// ctx is done or deadline passes.
+func (c *coalescing) do(ctx context.Context, deadline time.Time, key string, resp *fasthttp.Response, send func(resp *fasthttp.Response) error) error {
+	c.mu.Lock()
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		return call.wait(ctx, deadline, resp)
+	}
+	call := &coalescedCall{done: make(chan struct{}), err: errCoalescedPanic}
+	c.calls[key] = call
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	err := send(resp)
+	if err == nil {
+		call.resp = &fasthttp.Response{}
+		resp.CopyTo(call.resp)
+	}
+	call.err = err
+	return err
+}
+
+func (call *coalescedCall) wait(ctx context.Context, deadline time.Time, resp *fasthttp.Response) error {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	var canceled <-chan struct{}
+	if ctx != nil {
+		canceled = ctx.Done()
+	}
+	select {
+	case <-call.done:
+	case <-expired:
+		return fasthttp.ErrTimeout
+	case <-canceled:
+		return ctx.Err()
+	}
+	if call.err != nil {
+		return call.err
+	}
+	call.resp.CopyTo(resp)
+	return nil
+}
+
+func (fhc *fastHttpClient) coalesceKey(headers []string) string {
+	var key strings.Builder
+	key.Write(fhc.req.Header.Method())
+	key.WriteByte(' ')
+	if fhc.socket != "" {
+		key.WriteString(unixScheme + fhc.socket + " ")
+	}
+	key.Write(fhc.uri.FullURI())
+	for _, header := range headers {
+		key.WriteByte('\n')
+		key.WriteString(header)
+		key.WriteByte(':')
+		key.Write(fhc.req.Header.Peek(header))
+	}
+	return key.String()
+}
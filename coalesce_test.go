@@ -0,0 +1,153 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"github.com/remicro/api/net/rehttp"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFactory_Coalesce(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		data, err := reFastHttpFixture.Encoder().Encode(&Object{Label: string(ctx.Request.Header.Peek("X-Tenant"))})
+		assert.NoError(t, err)
+		ctx.Response.Header.SetContentType("application/json")
+		ctx.Write(data)
+	})
+	defer fx.Finish()
+
+	get := func(factory *Factory, tenant string, result *Object) (rehttp.Response, error) {
+		return factory.To(fx.Address()).
+			GET("/cached").
+			QueryParam("key", "value").
+			Header("X-Tenant", tenant).
+			Decoder(reFastHttpFixture.Decoder()).
+			ToDecode(result).
+			DecodeType(rehttp.ContentTypeJson).
+			Go()
+	}
+	waitAndRelease := func(expHits int32) {
+		eventually(t, func() bool {
+			return atomic.LoadInt32(&hits) == expHits
+		})
+		time.Sleep(50 * time.Millisecond)
+		release <- struct{}{}
+		for i := int32(1); i < expHits; i++ {
+			release <- struct{}{}
+		}
+	}
+
+	t.Run("expect identical requests to share one round trip", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().Coalesce("X-Tenant")
+		results := make([]Object, 10)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				res, err := get(factory, "acme", &results[i])
+				if assert.NoError(t, err) {
+					assert.Equal(t, fasthttp.StatusOK, res.Status())
+				}
+			}(i)
+		}
+		waitAndRelease(1)
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+		for _, result := range results {
+			assert.Equal(t, Object{Label: "acme"}, result)
+		}
+	})
+
+	t.Run("expect selected headers to tell requests apart", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().Coalesce("X-Tenant")
+		results := make([]Object, 2)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := get(factory, strconv.Itoa(i), &results[i])
+				assert.NoError(t, err)
+			}(i)
+		}
+		waitAndRelease(2)
+		wg.Wait()
+		assert.Equal(t, []Object{{Label: "0"}, {Label: "1"}}, results)
+	})
+
+	t.Run("expect credentials to tell requests apart", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().Coalesce()
+		var wg sync.WaitGroup
+		for _, token := range []string{"alice", "bob"} {
+			wg.Add(1)
+			go func(token string) {
+				defer wg.Done()
+				_, err := factory.To(fx.Address()).GET("/cached").Header("Authorization", "Bearer "+token).Go()
+				assert.NoError(t, err)
+			}(token)
+		}
+		waitAndRelease(2)
+		wg.Wait()
+		assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("expect followers to give up with their context", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().Coalesce()
+		leader := factory.To(fx.Address()).GET("/cached").(Builder).GoAsync()
+		eventually(t, func() bool {
+			return atomic.LoadInt32(&hits) == 1
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		_, err := factory.To(fx.Address()).GET("/cached").(Builder).Context(ctx).Go()
+		assert.True(t, errors.Is(err, context.Canceled))
+
+		deadline, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = factory.To(fx.Address()).GET("/cached").(Builder).Context(deadline).Go()
+		assert.True(t, err == fasthttp.ErrTimeout || errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+
+		release <- struct{}{}
+		_, err = leader.Wait()
+		assert.NoError(t, err)
+	})
+}
+
+func TestCoalescing_LeaderPanic(t *testing.T) {
+	c := &coalescing{calls: make(map[string]*coalescedCall)}
+	sending := make(chan struct{})
+	go func() {
+		defer func() {
+			recover()
+		}()
+		c.do(nil, time.Time{}, "key", &fasthttp.Response{}, func(resp *fasthttp.Response) error {
+			close(sending)
+			time.Sleep(50 * time.Millisecond)
+			panic("boom")
+		})
+	}()
+	<-sending
+	err := c.do(nil, time.Time{}, "key", &fasthttp.Response{}, func(resp *fasthttp.Response) error {
+		t.Error("follower must not send")
+		return nil
+	})
+	assert.Equal(t, errCoalescedPanic, err)
+	assert.Empty(t, c.calls)
+}
@@ -0,0 +1,251 @@
+package refasthttp
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func (res *responseImpl) Cookies() (cookies []*fasthttp.Cookie) {
+	res.response.Header.VisitAllCookie(func(key, value []byte) {
+		cookie := &fasthttp.Cookie{}
+		if err := cookie.ParseBytes(value); err != nil {
+			return
+		}
+		cookies = append(cookies, cookie)
+	})
+	return
+}
+
+// CookieJar keeps cookies received in Set-Cookie headers and attaches them
+// to subsequent requests following the RFC 6265 domain, path, secure and
+// expiry matching rules. A jar is safe for concurrent use.
+type CookieJar struct {
+	mu      sync.Mutex
+	entries map[string]map[string]*jarEntry
+	now     func() time.Time
+	seq     uint64
+}
+
+type jarEntry struct {
+	cookie   *fasthttp.Cookie
+	domain   string
+	path     string
+	hostOnly bool
+	secure   bool
+	expires  time.Time
+	seq      uint64
+}
+
+func NewCookieJar() *CookieJar {
+	return &CookieJar{
+		entries: make(map[string]map[string]*jarEntry),
+		now:     time.Now,
+	}
+}
+
+// SetCookies stores cookies as if they were received in a response to uri.
+func (jar *CookieJar) SetCookies(uri *fasthttp.URI, cookies ...*fasthttp.Cookie) {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+	for _, cookie := range cookies {
+		jar.set(uri, cookie, false)
+	}
+}
+
+// Cookies returns copies of the cookies which should be sent with a request to uri.
+func (jar *CookieJar) Cookies(uri *fasthttp.URI) (cookies []*fasthttp.Cookie) {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+	for _, entry := range jar.match(uri) {
+		cookie := &fasthttp.Cookie{}
+		cookie.CopyTo(entry.cookie)
+		cookies = append(cookies, cookie)
+	}
+	return
+}
+
+func (jar *CookieJar) attach(uri *fasthttp.URI, req *fasthttp.Request) {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+	for _, entry := range jar.match(uri) {
+		if len(req.Header.CookieBytes(entry.cookie.Key())) > 0 {
+			continue
+		}
+		req.Header.SetCookieBytesKV(entry.cookie.Key(), entry.cookie.Value())
+	}
+}
+
+func (jar *CookieJar) store(uri *fasthttp.URI, resp *fasthttp.Response) {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+	resp.Header.VisitAllCookie(func(key, value []byte) {
+		deleted := cookieMaxAgeExpired(value)
+		cookie := &fasthttp.Cookie{}
+		if err := cookie.ParseBytes(value); err != nil && !deleted {
+			return
+		}
+		if deleted {
+			cookie.SetKeyBytes(key)
+		}
+		jar.set(uri, cookie, deleted)
+	})
+}
+
+func (jar *CookieJar) set(uri *fasthttp.URI, cookie *fasthttp.Cookie, deleted bool) {
+	host := canonicalHost(uri.Host())
+	now := jar.now()
+
+	entry := &jarEntry{
+		domain:   host,
+		hostOnly: true,
+		path:     string(cookie.Path()),
+		secure:   cookie.Secure(),
+	}
+	if domain := strings.TrimPrefix(strings.ToLower(string(cookie.Domain())), "."); domain != "" {
+		if !domainMatch(host, domain) {
+			return
+		}
+		entry.domain, entry.hostOnly = domain, false
+	}
+	if entry.path == "" || entry.path[0] != '/' {
+		entry.path = defaultCookiePath(string(uri.Path()))
+	}
+	switch {
+	case deleted:
+		entry.expires = now
+	case cookie.MaxAge() > 0:
+		entry.expires = now.Add(time.Duration(cookie.MaxAge()) * time.Second)
+	case cookie.Expire() != fasthttp.CookieExpireUnlimited:
+		entry.expires = cookie.Expire()
+	}
+
+	key := entry.path + ";" + string(cookie.Key())
+	domainEntries := jar.entries[entry.domain]
+	if old, ok := domainEntries[key]; ok {
+		entry.seq = old.seq
+	} else {
+		jar.seq++
+		entry.seq = jar.seq
+	}
+	if entry.expired(now) {
+		delete(domainEntries, key)
+		if len(domainEntries) == 0 {
+			delete(jar.entries, entry.domain)
+		}
+		return
+	}
+	if domainEntries == nil {
+		domainEntries = make(map[string]*jarEntry)
+		jar.entries[entry.domain] = domainEntries
+	}
+	entry.cookie = &fasthttp.Cookie{}
+	entry.cookie.CopyTo(cookie)
+	entry.cookie.SetDomain(entry.domain)
+	entry.cookie.SetPath(entry.path)
+	domainEntries[key] = entry
+}
+
+func (jar *CookieJar) match(uri *fasthttp.URI) (entries []*jarEntry) {
+	host := canonicalHost(uri.Host())
+	path := string(uri.Path())
+	secure := bytes.EqualFold(uri.Scheme(), []byte("https"))
+	now := jar.now()
+
+	for domain, domainEntries := range jar.entries {
+		if !domainMatch(host, domain) {
+			continue
+		}
+		for key, entry := range domainEntries {
+			if entry.expired(now) {
+				delete(domainEntries, key)
+				continue
+			}
+			if entry.hostOnly && host != domain {
+				continue
+			}
+			if entry.secure && !secure {
+				continue
+			}
+			if !pathMatch(path, entry.path) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		if len(domainEntries) == 0 {
+			delete(jar.entries, domain)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if len(entries[i].path) != len(entries[j].path) {
+			return len(entries[i].path) > len(entries[j].path)
+		}
+		return entries[i].seq < entries[j].seq
+	})
+	return
+}
+
+func (entry *jarEntry) expired(now time.Time) bool {
+	return !entry.expires.IsZero() && !entry.expires.After(now)
+}
+
+func canonicalHost(host []byte) string {
+	h := strings.ToLower(string(host))
+	if hostname, _, err := net.SplitHostPort(h); err == nil {
+		h = hostname
+	}
+	return strings.TrimSuffix(h, ".")
+}
+
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	if net.ParseIP(host) != nil {
+		return false
+	}
+	return strings.HasSuffix(host, domain) && host[len(host)-len(domain)-1] == '.'
+}
+
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return cookiePath[len(cookiePath)-1] == '/' || requestPath[len(cookiePath)] == '/'
+}
+
+func defaultCookiePath(requestPath string) string {
+	if requestPath == "" || requestPath[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndexByte(requestPath, '/')
+	if i == 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// cookieMaxAgeExpired reports whether a Set-Cookie value carries a zero or
+// negative Max-Age, which fasthttp.Cookie can't represent.
+func cookieMaxAgeExpired(value []byte) bool {
+	for _, attr := range bytes.Split(value, []byte(";"))[1:] {
+		kv := bytes.SplitN(bytes.TrimSpace(attr), []byte("="), 2)
+		if len(kv) != 2 || !bytes.EqualFold(kv[0], []byte("max-age")) {
+			continue
+		}
+		maxAge, err := strconv.Atoi(string(bytes.TrimSpace(kv[1])))
+		return err == nil && maxAge <= 0
+	}
+	return false
+}
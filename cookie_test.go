@@ -0,0 +1,145 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+	"time"
+)
+
+func parseURI(t *testing.T, uri string) *fasthttp.URI {
+	u := &fasthttp.URI{}
+	require.NoError(t, u.Parse(nil, []byte(uri)))
+	return u
+}
+
+func newCookie(t *testing.T, raw string) *fasthttp.Cookie {
+	cookie := &fasthttp.Cookie{}
+	require.NoError(t, cookie.Parse(raw))
+	return cookie
+}
+
+func cookieNames(cookies []*fasthttp.Cookie) (names []string) {
+	for _, cookie := range cookies {
+		names = append(names, string(cookie.Key()))
+	}
+	return
+}
+
+func TestResponseImpl_Cookies(t *testing.T) {
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Add("Set-Cookie", "session=abc; Domain=example.com; Path=/api; Secure; SameSite=Strict")
+		ctx.Response.Header.Add("Set-Cookie", "theme=dark; Max-Age=60")
+	})
+	defer fx.Finish()
+
+	res, err := New().
+		Address(fx.Address()).
+		GET("/").
+		Go()
+	require.NoError(t, err)
+
+	cookies := res.(Response).Cookies()
+	require.Len(t, cookies, 2)
+	byName := map[string]*fasthttp.Cookie{}
+	for _, cookie := range cookies {
+		byName[string(cookie.Key())] = cookie
+	}
+	session := byName["session"]
+	require.NotNil(t, session)
+	assert.Equal(t, "abc", string(session.Value()))
+	assert.Equal(t, "example.com", string(session.Domain()))
+	assert.Equal(t, "/api", string(session.Path()))
+	assert.True(t, session.Secure())
+	assert.Equal(t, fasthttp.CookieSameSiteStrictMode, session.SameSite())
+	assert.Equal(t, 60, byName["theme"].MaxAge())
+}
+
+func TestCookieJar(t *testing.T) {
+	t.Run("expect host only cookie not to match subdomain", func(t *testing.T) {
+		jar := NewCookieJar()
+		jar.SetCookies(parseURI(t, "http://example.com/"), newCookie(t, "a=1"))
+		assert.Equal(t, []string{"a"}, cookieNames(jar.Cookies(parseURI(t, "http://example.com/x"))))
+		assert.Empty(t, jar.Cookies(parseURI(t, "http://api.example.com/")))
+	})
+
+	t.Run("expect domain cookie to match subdomain", func(t *testing.T) {
+		jar := NewCookieJar()
+		jar.SetCookies(parseURI(t, "http://api.example.com/"), newCookie(t, "a=1; Domain=.example.com"))
+		assert.Equal(t, []string{"a"}, cookieNames(jar.Cookies(parseURI(t, "http://www.example.com/"))))
+		assert.Empty(t, jar.Cookies(parseURI(t, "http://badexample.com/")))
+	})
+
+	t.Run("expect foreign domain to be rejected", func(t *testing.T) {
+		jar := NewCookieJar()
+		jar.SetCookies(parseURI(t, "http://example.com/"), newCookie(t, "a=1; Domain=other.com"))
+		assert.Empty(t, jar.Cookies(parseURI(t, "http://other.com/")))
+	})
+
+	t.Run("expect path matching and ordering", func(t *testing.T) {
+		jar := NewCookieJar()
+		uri := parseURI(t, "http://example.com/")
+		jar.SetCookies(uri, newCookie(t, "root=1; Path=/"), newCookie(t, "api=1; Path=/api"))
+		assert.Equal(t, []string{"api", "root"}, cookieNames(jar.Cookies(parseURI(t, "http://example.com/api/users"))))
+		assert.Equal(t, []string{"root"}, cookieNames(jar.Cookies(parseURI(t, "http://example.com/apiary"))))
+	})
+
+	t.Run("expect default path from request uri", func(t *testing.T) {
+		jar := NewCookieJar()
+		jar.SetCookies(parseURI(t, "http://example.com/a/b"), newCookie(t, "a=1"))
+		assert.Empty(t, jar.Cookies(parseURI(t, "http://example.com/")))
+		assert.Len(t, jar.Cookies(parseURI(t, "http://example.com/a/c")), 1)
+	})
+
+	t.Run("expect secure cookie only over https", func(t *testing.T) {
+		jar := NewCookieJar()
+		jar.SetCookies(parseURI(t, "https://example.com/"), newCookie(t, "a=1; Secure"))
+		assert.Empty(t, jar.Cookies(parseURI(t, "http://example.com/")))
+		assert.Len(t, jar.Cookies(parseURI(t, "https://example.com/")), 1)
+	})
+
+	t.Run("expect expired cookies to be dropped", func(t *testing.T) {
+		jar := NewCookieJar()
+		now := time.Now()
+		jar.now = func() time.Time { return now }
+		uri := parseURI(t, "http://example.com/")
+		jar.SetCookies(uri, newCookie(t, "a=1; Max-Age=10"))
+		assert.Len(t, jar.Cookies(uri), 1)
+		now = now.Add(11 * time.Second)
+		assert.Empty(t, jar.Cookies(uri))
+	})
+
+	t.Run("expect cookies to be stored and attached across requests", func(t *testing.T) {
+		value := trifle.String()
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			switch string(ctx.Path()) {
+			case "/login":
+				ctx.Response.Header.Add("Set-Cookie", "session="+value+"; Path=/")
+			case "/logout":
+				assert.Equal(t, value, string(ctx.Request.Header.Cookie("session")))
+				ctx.Response.Header.Add("Set-Cookie", "session=; Max-Age=0; Path=/")
+			default:
+				ctx.Write(ctx.Request.Header.Cookie("session"))
+			}
+		})
+		defer fx.Finish()
+
+		factory := NewFactory().CookieJar(NewCookieJar())
+		_, err := factory.To(fx.Address()).GET("/login").Go()
+		require.NoError(t, err)
+
+		res, err := factory.To(fx.Address()).GET("/me").Go()
+		require.NoError(t, err)
+		assert.Equal(t, value, string(res.Body()))
+
+		_, err = factory.To(fx.Address()).GET("/logout").Go()
+		require.NoError(t, err)
+
+		res, err = factory.To(fx.Address()).GET("/me").Go()
+		require.NoError(t, err)
+		assert.Empty(t, res.Body())
+	})
+}
@@ -0,0 +1,81 @@
+package refasthttp
+
+import (
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const unixScheme = "unix://"
+
+// Dialer opens the connections of a Factory. addr is a "host:port" pair.
+type Dialer interface {
+	Dial(addr string) (conn net.Conn, err error)
+}
+
+type DialerFunc func(addr string) (conn net.Conn, err error)
+
+func (fn DialerFunc) Dial(addr string) (conn net.Conn, err error) {
+	return fn(addr)
+}
+
+type transport interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+	DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error
+}
+
+func (f *Factory) Dialer(dialer Dialer) *Factory {
+	f.dialer = dialer
+	return f
+}
+
+// sockets keeps one host client per unix socket path.
+type sockets struct {
+	mu      sync.Mutex
+	clients map[string]*fasthttp.HostClient
+}
+
+func (f *Factory) socketClient(path string) *fasthttp.HostClient {
+	f.sockets.mu.Lock()
+	defer f.sockets.mu.Unlock()
+	if client, ok := f.sockets.clients[path]; ok {
+		return client
+	}
+	if f.sockets.clients == nil {
+		f.sockets.clients = make(map[string]*fasthttp.HostClient)
+	}
+	src := f.client
+	client := &fasthttp.HostClient{
+		Addr: path,
+		Dial: f.pool.track(untimed(func(addr string) (net.Conn, error) {
+			return net.Dial("unix", addr)
+		})),
+		Name:                          src.Name,
+		NoDefaultUserAgentHeader:      src.NoDefaultUserAgentHeader,
+		MaxConns:                      src.MaxConnsPerHost,
+		MaxIdleConnDuration:           src.MaxIdleConnDuration,
+		MaxConnDuration:               src.MaxConnDuration,
+		MaxIdemponentCallAttempts:     src.MaxIdemponentCallAttempts,
+		ReadBufferSize:                src.ReadBufferSize,
+		WriteBufferSize:               src.WriteBufferSize,
+		ReadTimeout:                   src.ReadTimeout,
+		WriteTimeout:                  src.WriteTimeout,
+		MaxResponseBodySize:           src.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: src.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        src.DisablePathNormalizing,
+		MaxConnWaitTimeout:            src.MaxConnWaitTimeout,
+		RetryIf:                       src.RetryIf,
+	}
+	f.sockets.clients[path] = client
+	return client
+}
+
+// parseUnixAddress splits "unix:///var/run/x.sock" into the socket path.
+func parseUnixAddress(address string) (path string, ok bool) {
+	if !strings.HasPrefix(address, unixScheme) {
+		return
+	}
+	return strings.TrimPrefix(address, unixScheme), true
+}
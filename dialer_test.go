@@ -0,0 +1,67 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFastHttpClient_UnixSocket(t *testing.T) {
+	fx := reFastHttpFixture.NewUnix(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Write(ctx.Host())
+		ctx.Write([]byte(" "))
+		ctx.Write(ctx.Path())
+	})
+	defer fx.Finish()
+
+	t.Run("expect request over unix socket", func(t *testing.T) {
+		res, err := New().Address(fx.Address()).GET("/status").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "localhost /status", string(res.Body()))
+	})
+
+	t.Run("expect separate host header", func(t *testing.T) {
+		host := strings.ToLower(trifle.String())
+		res, err := New().Address(fx.Address()).Header("Host", host).GET("/status").Go()
+		require.NoError(t, err)
+		assert.Equal(t, host+" /status", string(res.Body()))
+	})
+}
+
+func TestFactory_Dialer(t *testing.T) {
+	t.Run("expect in-memory listener to be reached through dialer", func(t *testing.T) {
+		fx := reFastHttpFixture.NewInmemory(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString("OK")
+		})
+		defer fx.Finish()
+
+		var mu sync.Mutex
+		var dialed []string
+		factory := NewFactory().Dialer(DialerFunc(func(addr string) (net.Conn, error) {
+			mu.Lock()
+			dialed = append(dialed, addr)
+			mu.Unlock()
+			return fx.Dial(addr)
+		}))
+		res, err := factory.To("http://inmemory.local").GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "OK", string(res.Body()))
+		assert.Equal(t, []string{"inmemory.local:80"}, dialed)
+	})
+
+	t.Run("expect dialer errors to be returned", func(t *testing.T) {
+		expErr := trifle.UnexpectedError()
+		factory := NewFactory().Dialer(DialerFunc(func(addr string) (net.Conn, error) {
+			return nil, expErr
+		}))
+		res, err := factory.To("http://" + trifle.String()).GET("/").Go()
+		assert.Equal(t, expErr, err)
+		assert.Nil(t, res)
+	})
+}
@@ -0,0 +1,179 @@
+package refasthttp
+
+import (
+	"crypto/tls"
+	"github.com/remicro/api/cloud/balancer"
+	"github.com/remicro/api/logging"
+	"github.com/remicro/api/net/rehttp"
+	"github.com/valyala/fasthttp"
+	"net"
+	"time"
+)
+
+var defaultFactory = NewFactory()
+
+// Factory shares a single fasthttp client and its settings between all
+// builders it creates. It must be configured before the first request.
+type Factory struct {
+	client       *fasthttp.Client
+	logger       logging.Logger
+	bln          balancer.Balancer
+	jar          *CookieJar
+	tls          *tls.Config
+	pinning      *Pinning
+	pinned       pinnedClient
+	proxy        *ProxyConfig
+	proxies      proxies
+	dialer       Dialer
+	resolver     *DualStackDialer
+	sockets      sockets
+	pool         pool
+	pipeline     *PipelineConfig
+	pipelines    pipelines
+	async        asyncPool
+	warmupBudget time.Duration
+	hedging      *hedging
+	coalescing   *coalescing
+	limiters     limiters
+	throttling   *throttling
+	bulkheads    bulkheads
+	accessLog    *AccessLogConfig
+	redaction    Redaction
+	metrics      Metrics
+	tracer       Tracer
+}
+
+func NewFactory() *Factory {
+	f := &Factory{
+		client: &fasthttp.Client{
+			RetryIf: retryIf,
+			// requests take the URI of the builder, which is normalized
+			// already unless path params were escaped into it
+			DisablePathNormalizing: true,
+		},
+		logger:    dummyLogger{},
+		redaction: DefaultRedaction(),
+		async: asyncPool{
+			workers: defaultAsyncWorkers,
+			queue:   defaultAsyncQueue,
+		},
+	}
+	f.client.Dial = f.pool.track(f.dialTimed)
+	return f
+}
+
+func (f *Factory) Logger(logger logging.Logger) *Factory {
+	f.logger = logger
+	return f
+}
+
+func (f *Factory) Balancer(bln balancer.Balancer) *Factory {
+	f.bln = bln
+	return f
+}
+
+func (f *Factory) CookieJar(jar *CookieJar) *Factory {
+	f.jar = jar
+	return f
+}
+
+func (f *Factory) New() Builder {
+	return &fastHttpClient{
+		req:     fasthttp.AcquireRequest(),
+		uri:     fasthttp.AcquireURI(),
+		query:   fasthttp.AcquireArgs(),
+		logger:  f.logger,
+		bln:     f.bln,
+		factory: f,
+	}
+}
+
+func (f *Factory) To(address string) rehttp.Builder {
+	return f.New().Address(address)
+}
+
+func (f *Factory) Service(name string) rehttp.Builder {
+	return f.New().Service(name)
+}
+
+// transportFor picks the client for the request of fhc: the unix socket
+// client, a client tunneling through the selected proxy, the pipeline client
+// of the host, the pinned client for https or the shared one.
+func (f *Factory) transportFor(fhc *fastHttpClient) (t transport, err error) {
+	if fhc.socket != "" {
+		return f.socketClient(fhc.socket), nil
+	}
+	var proxyURL string
+	switch {
+	case fhc.proxy != nil:
+		proxyURL = *fhc.proxy
+	case f.proxy != nil:
+		proxyURL = f.proxy.proxyFor(fhc.uri)
+	}
+	switch {
+	case proxyURL != "":
+		return f.proxyClient(proxyURL, fhc.pinned())
+	case f.pipeline != nil:
+		return f.pipelineClient(fhc), nil
+	case fhc.pinned():
+		return f.pinnedClient(), nil
+	}
+	return f.client, nil
+}
+
+// pinnedClient is the shared client for https requests when the factory
+// pins certificates, its dial function does the TLS handshake itself.
+func (f *Factory) pinnedClient() *fasthttp.Client {
+	f.pinned.mu.Lock()
+	defer f.pinned.mu.Unlock()
+	if f.pinned.client == nil {
+		f.pinned.client = f.cloneClient(f.pinning.dial(f.client.Dial, f.tls))
+	}
+	return f.pinned.client
+}
+
+func (f *Factory) dialDirect(addr string) (net.Conn, error) {
+	if f.dialer != nil {
+		return f.dialer.Dial(addr)
+	}
+	if f.resolver != nil {
+		return f.resolver.Dial(addr)
+	}
+	return fasthttp.Dial(addr)
+}
+
+// dialTimed is dialDirect reporting how long resolving the host took, which
+// is only known when the factory resolves hosts itself.
+func (f *Factory) dialTimed(addr string) (net.Conn, time.Duration, error) {
+	if f.dialer == nil && f.resolver != nil {
+		return f.resolver.dialTimed(addr)
+	}
+	conn, err := f.dialDirect(addr)
+	return conn, 0, err
+}
+
+// cloneClient copies the settings of the shared client into a new one with
+// its own connection pools and dial function.
+func (f *Factory) cloneClient(dial fasthttp.DialFunc) *fasthttp.Client {
+	src := f.client
+	return &fasthttp.Client{
+		Name:                          src.Name,
+		NoDefaultUserAgentHeader:      src.NoDefaultUserAgentHeader,
+		Dial:                          dial,
+		DialDualStack:                 src.DialDualStack,
+		TLSConfig:                     src.TLSConfig,
+		MaxConnsPerHost:               src.MaxConnsPerHost,
+		MaxIdleConnDuration:           src.MaxIdleConnDuration,
+		MaxConnDuration:               src.MaxConnDuration,
+		MaxIdemponentCallAttempts:     src.MaxIdemponentCallAttempts,
+		ReadBufferSize:                src.ReadBufferSize,
+		WriteBufferSize:               src.WriteBufferSize,
+		ReadTimeout:                   src.ReadTimeout,
+		WriteTimeout:                  src.WriteTimeout,
+		MaxResponseBodySize:           src.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: src.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        src.DisablePathNormalizing,
+		MaxConnWaitTimeout:            src.MaxConnWaitTimeout,
+		RetryIf:                       src.RetryIf,
+	}
+}
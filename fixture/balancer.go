@@ -0,0 +1,103 @@
+package reFastHttpFixture
+
+import (
+	"github.com/remicro/api/cloud/balancer"
+	"github.com/remicro/api/cloud/discovery"
+	"net/url"
+	"sync"
+)
+
+type node struct {
+	name    string
+	address string
+}
+
+func (n node) ID() string {
+	return n.name + "@" + n.address
+}
+
+func (n node) Address() string {
+	return n.address
+}
+
+func (n node) Schema() string {
+	u, err := url.Parse(n.address)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme
+}
+
+func (n node) Name() string {
+	return n.name
+}
+
+func (n node) Version() string {
+	return ""
+}
+
+func (n node) Options() []discovery.Option {
+	return nil
+}
+
+// RoundRobin is a balancer over a static set of addresses per service.
+// Declined nodes are skipped until every node of the service is declined.
+type RoundRobin struct {
+	mu       sync.Mutex
+	services map[string][]discovery.Node
+	next     map[string]int
+	declined map[string]bool
+}
+
+func Balancer(services map[string][]string) *RoundRobin {
+	rr := &RoundRobin{
+		services: make(map[string][]discovery.Node),
+		next:     make(map[string]int),
+		declined: make(map[string]bool),
+	}
+	for name, addresses := range services {
+		for _, address := range addresses {
+			rr.services[name] = append(rr.services[name], node{name: name, address: address})
+		}
+	}
+	return rr
+}
+
+func (rr *RoundRobin) Find(name string) (found discovery.Node, err error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	nodes, ok := rr.services[name]
+	if !ok || len(nodes) == 0 {
+		err = balancer.ErrUnknownService
+		return
+	}
+	for range nodes {
+		candidate := nodes[rr.next[name]%len(nodes)]
+		rr.next[name]++
+		if !rr.declined[candidate.ID()] {
+			return candidate, nil
+		}
+	}
+	err = balancer.ErrAllDeclined
+	return
+}
+
+func (rr *RoundRobin) Decline(declined discovery.Node) (nextNode discovery.Node, err error) {
+	rr.mu.Lock()
+	rr.declined[declined.ID()] = true
+	rr.mu.Unlock()
+	return rr.Find(declined.Name())
+}
+
+// Nodes lists every node registered for the service.
+func (rr *RoundRobin) Nodes(name string) (nodes []discovery.Node, err error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	registered, ok := rr.services[name]
+	if !ok {
+		err = balancer.ErrUnknownService
+		return
+	}
+	nodes = append(nodes, registered...)
+	return
+}
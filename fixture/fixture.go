@@ -3,14 +3,17 @@ package reFastHttpFixture
 import (
 	"github.com/stretchr/testify/require"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
 	"net"
+	"path/filepath"
 	"testing"
 )
 
 type Fixture struct {
 	l net.Listener
 	*fasthttp.Server
-	t *testing.T
+	t      *testing.T
+	scheme string
 }
 
 func (fx *Fixture) Finish() {
@@ -18,13 +21,14 @@ func (fx *Fixture) Finish() {
 }
 
 func (fx *Fixture) Address() string {
-	return "http://" + fx.l.Addr().String()
+	return fx.scheme + "://" + fx.l.Addr().String()
 }
 
 func New(t *testing.T, handler fasthttp.RequestHandler) *Fixture {
 	fx := &Fixture{
 		Server: &fasthttp.Server{},
 		t:      t,
+		scheme: "http",
 	}
 
 	fx.Handler = handler
@@ -35,3 +39,44 @@ func New(t *testing.T, handler fasthttp.RequestHandler) *Fixture {
 
 	return fx
 }
+
+// NewUnix serves handler over a unix domain socket, Address returns a
+// "unix://" address.
+func NewUnix(t *testing.T, handler fasthttp.RequestHandler) *Fixture {
+	fx := &Fixture{
+		Server: &fasthttp.Server{},
+		t:      t,
+		scheme: "unix",
+	}
+
+	fx.Handler = handler
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "fixture.sock"))
+	require.NoError(t, err)
+	fx.l = l
+	go fx.Server.Serve(l)
+
+	return fx
+}
+
+// NewInmemory serves handler over an in-memory listener, connections are
+// opened with Dial.
+func NewInmemory(t *testing.T, handler fasthttp.RequestHandler) *Fixture {
+	fx := &Fixture{
+		Server: &fasthttp.Server{},
+		t:      t,
+		scheme: "http",
+	}
+
+	fx.Handler = handler
+	fx.l = fasthttputil.NewInmemoryListener()
+	go fx.Server.Serve(fx.l)
+
+	return fx
+}
+
+func (fx *Fixture) Dial(addr string) (net.Conn, error) {
+	if l, ok := fx.l.(*fasthttputil.InmemoryListener); ok {
+		return l.Dial()
+	}
+	return net.Dial(fx.l.Addr().Network(), fx.l.Addr().String())
+}
@@ -0,0 +1,183 @@
+package reFastHttpFixture
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Proxy is a local stand-in for an egress proxy. It speaks either HTTP
+// CONNECT or SOCKS5, optionally requires credentials and records the targets
+// of the tunnels it opened.
+type Proxy struct {
+	l        net.Listener
+	t        *testing.T
+	scheme   string
+	user     string
+	password string
+	mu       sync.Mutex
+	targets  []string
+}
+
+func NewHTTPProxy(t *testing.T, user, password string) *Proxy {
+	return startProxy(t, "http", user, password)
+}
+
+func NewSOCKS5Proxy(t *testing.T, user, password string) *Proxy {
+	return startProxy(t, "socks5", user, password)
+}
+
+func startProxy(t *testing.T, scheme, user, password string) *Proxy {
+	l, err := net.Listen("tcp4", "localhost:0")
+	require.NoError(t, err)
+	proxy := &Proxy{
+		l:        l,
+		t:        t,
+		scheme:   scheme,
+		user:     user,
+		password: password,
+	}
+	go proxy.serve()
+	return proxy
+}
+
+// URL returns the proxy address including the given credentials.
+func (p *Proxy) URL(user, password string) string {
+	credentials := ""
+	if user != "" {
+		credentials = user + ":" + password + "@"
+	}
+	return p.scheme + "://" + credentials + p.l.Addr().String()
+}
+
+func (p *Proxy) Targets() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...)
+}
+
+func (p *Proxy) Finish() {
+	require.NoError(p.t, p.l.Close())
+}
+
+func (p *Proxy) serve() {
+	for {
+		conn, err := p.l.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *Proxy) handle(conn net.Conn) {
+	defer conn.Close()
+	var target string
+	var ok bool
+	if p.scheme == "socks5" {
+		target, ok = p.socks5(conn)
+	} else {
+		target, ok = p.connect(conn)
+	}
+	if !ok {
+		return
+	}
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	p.mu.Lock()
+	p.targets = append(p.targets, target)
+	p.mu.Unlock()
+	if p.scheme == "socks5" {
+		conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	} else {
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	}
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func (p *Proxy) connect(conn net.Conn) (target string, ok bool) {
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+		return
+	}
+	if p.user != "" {
+		expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(p.user+":"+p.password))
+		if req.Header.Get("Proxy-Authorization") != expected {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+	}
+	return req.Host, true
+}
+
+func (p *Proxy) socks5(conn net.Conn) (target string, ok bool) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	if p.user == "" {
+		conn.Write([]byte{0x05, 0x00})
+	} else {
+		conn.Write([]byte{0x05, 0x02})
+		version := make([]byte, 2)
+		if _, err := io.ReadFull(conn, version); err != nil {
+			return
+		}
+		user := make([]byte, version[1])
+		io.ReadFull(conn, user)
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		password := make([]byte, length[0])
+		io.ReadFull(conn, password)
+		if string(user) != p.user || string(password) != p.password {
+			conn.Write([]byte{0x01, 0x01})
+			return
+		}
+		conn.Write([]byte{0x01, 0x00})
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 0x01:
+		ip := make([]byte, net.IPv4len)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 0x04:
+		ip := make([]byte, net.IPv6len)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 0x03:
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		name := make([]byte, length[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), true
+}
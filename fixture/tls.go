@@ -0,0 +1,134 @@
+package reFastHttpFixture
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Certificates is a throwaway PKI: a CA, a server certificate for localhost
+// and a client certificate, all written as PEM files into a temp directory.
+type Certificates struct {
+	Dir            string
+	CAFile         string
+	ServerCertFile string
+	ServerKeyFile  string
+	ClientCertFile string
+	ClientKeyFile  string
+	ServerCert     tls.Certificate
+	ca             *x509.Certificate
+	caKey          *ecdsa.PrivateKey
+	t              *testing.T
+}
+
+func NewCertificates(t *testing.T) *Certificates {
+	certs := &Certificates{
+		Dir: t.TempDir(),
+		t:   t,
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := certificateTemplate("fixture ca")
+	template.IsCA = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certs.ca, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	certs.caKey = key
+	certs.CAFile = certs.writePEM("ca.pem", "CERTIFICATE", der)
+
+	certs.ServerCertFile, certs.ServerKeyFile = certs.Issue("server", "localhost")
+	certs.ServerCert, err = tls.LoadX509KeyPair(certs.ServerCertFile, certs.ServerKeyFile)
+	require.NoError(t, err)
+	certs.ClientCertFile, certs.ClientKeyFile = certs.Issue("client")
+	return certs
+}
+
+// Issue signs a new certificate with the fixture CA. Additional hosts are
+// added as DNS names, localhost certificates get the loopback addresses too.
+func (certs *Certificates) Issue(commonName string, hosts ...string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(certs.t, err)
+	template := certificateTemplate(commonName)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	for _, host := range hosts {
+		template.DNSNames = append(template.DNSNames, host)
+		if host == "localhost" {
+			template.IPAddresses = append(template.IPAddresses, net.IPv4(127, 0, 0, 1), net.IPv6loopback)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, certs.ca, &key.PublicKey, certs.caKey)
+	require.NoError(certs.t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(certs.t, err)
+
+	certFile = certs.writePEM(commonName+".pem", "CERTIFICATE", der)
+	keyFile = certs.writePEM(commonName+"-key.pem", "EC PRIVATE KEY", keyDer)
+	return
+}
+
+func (certs *Certificates) writePEM(name, blockType string, der []byte) string {
+	path := filepath.Join(certs.Dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(certs.t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func (certs *Certificates) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(certs.ca)
+	return pool
+}
+
+func certificateTemplate(commonName string) *x509.Certificate {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		BasicConstraintsValid: true,
+	}
+}
+
+// NewTLS starts an https fixture server using the server certificate of certs.
+// With tls.RequireAndVerifyClientCert the client has to present a certificate
+// signed by the fixture CA.
+func NewTLS(t *testing.T, certs *Certificates, clientAuth tls.ClientAuthType, handler fasthttp.RequestHandler) *Fixture {
+	fx := &Fixture{
+		Server: &fasthttp.Server{
+			Logger: log.New(ioutil.Discard, "", 0),
+		},
+		t:      t,
+		scheme: "https",
+	}
+
+	fx.Handler = handler
+	l, err := net.Listen("tcp4", "localhost:0")
+	require.NoError(t, err)
+	fx.l = tls.NewListener(l, &tls.Config{
+		Certificates: []tls.Certificate{certs.ServerCert},
+		ClientAuth:   clientAuth,
+		ClientCAs:    certs.Pool(),
+		// resumed sessions would hide rotated client certificates
+		SessionTicketsDisabled: true,
+	})
+	go fx.Server.Serve(fx.l)
+
+	return fx
+}
@@ -0,0 +1,235 @@
+package refasthttp
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHedgeDelay     = 100 * time.Millisecond
+	defaultHedgeExtraLoad = 0.1
+	hedgeLatencySamples   = 128
+	hedgeMinSamples       = 20
+	hedgeNodeAttempts     = 3
+)
+
+// HedgePolicy makes GET and HEAD requests to services, and requests marked
+// Idempotent, send a second attempt to another node when the first one is
+// slow. The first response wins, the
+// other attempt is abandoned and its response dropped.
+type HedgePolicy struct {
+	// Delay before the second attempt, 100ms when zero. It is also used
+	// until enough latencies are observed for Percentile.
+	Delay time.Duration
+	// Percentile of the observed latencies of the service to wait instead of
+	// Delay, e.g. 0.95. Zero keeps the fixed delay.
+	Percentile float64
+	// MaxExtraLoad caps hedged attempts to this fraction of the requests of
+	// the service, 0.1 when zero.
+	MaxExtraLoad float64
+}
+
+func (f *Factory) Hedging(policy HedgePolicy) *Factory {
+	if policy.Delay <= 0 {
+		policy.Delay = defaultHedgeDelay
+	}
+	if policy.MaxExtraLoad <= 0 {
+		policy.MaxExtraLoad = defaultHedgeExtraLoad
+	}
+	f.hedging = &hedging{policy: policy, services: make(map[string]*hedgeStats)}
+	return f
+}
+
+type hedging struct {
+	policy   HedgePolicy
+	mu       sync.Mutex
+	services map[string]*hedgeStats
+}
+
+type hedgeStats struct {
+	requests  uint64
+	hedges    uint64
+	latencies []time.Duration
+	next      int
+}
+
+// begin counts a request of service and returns how long to wait before
+// hedging it.
+func (h *hedging) begin(service string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.stats(service)
+	stats.requests++
+	if h.policy.Percentile <= 0 || len(stats.latencies) < hedgeMinSamples {
+		return h.policy.Delay
+	}
+	sorted := append([]time.Duration(nil), stats.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(h.policy.Percentile * float64(len(sorted)))
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// allow reserves a hedged attempt if it stays within the extra load cap.
+func (h *hedging) allow(service string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.stats(service)
+	if float64(stats.hedges+1) > h.policy.MaxExtraLoad*float64(stats.requests) {
+		return false
+	}
+	stats.hedges++
+	return true
+}
+
+func (h *hedging) observe(service string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.stats(service)
+	if len(stats.latencies) < hedgeLatencySamples {
+		stats.latencies = append(stats.latencies, latency)
+		return
+	}
+	stats.latencies[stats.next] = latency
+	stats.next = (stats.next + 1) % hedgeLatencySamples
+}
+
+func (h *hedging) stats(service string) *hedgeStats {
+	stats, ok := h.services[service]
+	if !ok {
+		stats = &hedgeStats{}
+		h.services[service] = stats
+	}
+	return stats
+}
+
+func (fhc *fastHttpClient) hedgeable() bool {
+	if fhc.factory.hedging == nil || fhc.service == "" || fhc.socket != "" || fhc.bln == nil {
+		return false
+	}
+	return fhc.req.Header.IsGet() || fhc.req.Header.IsHead() ||
+		len(fhc.req.Header.Peek(HeaderIdempotencyKey)) > 0
+}
+
+type hedgeAttempt struct {
+	attempt *fastHttpClient
+	resp    *fasthttp.Response
+	err     error
+}
+
+// doHedged runs the request on a copy of fhc and hedges it with a copy sent
+// to another node of the service, the response which arrives first is
+// copied into resp. The copies carry the deadline of the call, which is the
+// only bound of the losing attempt since fasthttp can't abort a Do in flight.
+func (fhc *fastHttpClient) doHedged(resp *fasthttp.Response) (err error) {
+	h := fhc.factory.hedging
+	delay := h.begin(fhc.service)
+	results := make(chan hedgeAttempt, 2)
+	send := func(attempt *fastHttpClient) {
+		started := time.Now()
+		attemptResp := fasthttp.AcquireResponse()
+		attemptErr := attempt.do(attemptResp)
+		if attemptErr == nil {
+			h.observe(fhc.service, time.Since(started))
+		}
+		results <- hedgeAttempt{attempt: attempt, resp: attemptResp, err: attemptErr}
+	}
+	go send(fhc.clone())
+	fhc.attempts++
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending := 1
+	var first hedgeAttempt
+	select {
+	case first = <-results:
+		pending--
+	case <-timer.C:
+		if hedge := fhc.hedgeAttempt(); hedge != nil {
+			go send(hedge)
+			fhc.attempts++
+			pending++
+		}
+		first = <-results
+		pending--
+	}
+	if first.err != nil && pending > 0 {
+		first.release()
+		first = <-results
+		pending--
+	}
+	if pending > 0 {
+		// fasthttp can't abort a Do in flight, the loser runs until it is
+		// answered or the deadline of the call passes and is released then,
+		// holding the bulkhead slot of the call until that
+		lease := fhc.lease
+		lease.retain()
+		go func() {
+			loser := <-results
+			loser.release()
+			lease.release()
+		}()
+	}
+	defer first.release()
+	if first.err != nil {
+		return first.err
+	}
+	first.resp.CopyTo(resp)
+	first.attempt.uri.CopyTo(fhc.uri)
+	fhc.timing.add(first.attempt.timing)
+	return
+}
+
+func (ha hedgeAttempt) release() {
+	fasthttp.ReleaseResponse(ha.resp)
+	fasthttp.ReleaseRequest(ha.attempt.req)
+	fasthttp.ReleaseURI(ha.attempt.uri)
+}
+
+// hedgeAttempt copies fhc to a different node of the service, nil when the
+// balancer has no other node or the extra load cap is reached.
+func (fhc *fastHttpClient) hedgeAttempt() *fastHttpClient {
+	target := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(target)
+	for i := 0; i < hedgeNodeAttempts; i++ {
+		node, err := fhc.bln.Find(fhc.service)
+		if err != nil {
+			return nil
+		}
+		if _, unix := parseUnixAddress(node.Address()); unix {
+			continue
+		}
+		target.Parse(nil, []byte(node.Address()))
+		if bytes.EqualFold(target.Host(), fhc.uri.Host()) {
+			continue
+		}
+		if !fhc.factory.hedging.allow(fhc.service) {
+			return nil
+		}
+		if pinning := fhc.factory.pinning; pinning != nil {
+			pinning.bindService(node.Address(), fhc.service)
+		}
+		hedge := fhc.clone()
+		hedge.uri.SetSchemeBytes(target.Scheme())
+		hedge.uri.SetHostBytes(target.Host())
+		return hedge
+	}
+	return nil
+}
+
+// clone copies the request state of fhc so the copy can be sent
+// concurrently with fhc.
+func (fhc *fastHttpClient) clone() *fastHttpClient {
+	c := *fhc
+	c.timing = Timing{}
+	c.req = fasthttp.AcquireRequest()
+	fhc.req.CopyTo(c.req)
+	c.uri = fasthttp.AcquireURI()
+	fhc.uri.CopyTo(c.uri)
+	return &c
+}
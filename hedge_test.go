@@ -0,0 +1,159 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFactory_Hedging(t *testing.T) {
+	var slowHits, fastHits int32
+	slow := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&slowHits, 1)
+		time.Sleep(150 * time.Millisecond)
+		ctx.WriteString("slow")
+	})
+	defer slow.Finish()
+	fast := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&fastHits, 1)
+		ctx.WriteString("fast")
+	})
+	defer fast.Finish()
+	reset := func() {
+		atomic.StoreInt32(&slowHits, 0)
+		atomic.StoreInt32(&fastHits, 0)
+	}
+	nodes := map[string][]string{"service": {slow.Address(), fast.Address()}}
+
+	t.Run("expect hedged attempt to win on slow node", func(t *testing.T) {
+		reset()
+		factory := NewFactory().
+			Balancer(reFastHttpFixture.Balancer(nodes)).
+			Hedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxExtraLoad: 1})
+		started := time.Now()
+		res, err := factory.Service("service").GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "fast", string(res.Body()))
+		assert.True(t, time.Since(started) < 150*time.Millisecond)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&slowHits))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&fastHits))
+	})
+
+	t.Run("expect losing attempt to be bound by the deadline of the call", func(t *testing.T) {
+		release := make(chan struct{})
+		stuck := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			<-release
+		})
+		defer stuck.Finish()
+		defer close(release)
+		factory := NewFactory().
+			Balancer(reFastHttpFixture.Balancer(map[string][]string{"service": {stuck.Address(), fast.Address()}})).
+			Hedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxExtraLoad: 1})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		res, err := factory.Service("service").GET("/").(Builder).Context(ctx).Go()
+		require.NoError(t, err)
+		assert.Equal(t, "fast", string(res.Body()))
+		eventually(t, func() bool {
+			return factory.Stats()[fixtureHost(stuck)].InUse == 0
+		})
+	})
+
+	t.Run("expect losing attempt to hold the bulkhead slot", func(t *testing.T) {
+		release := make(chan struct{})
+		stuck := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			<-release
+		})
+		defer stuck.Finish()
+		factory := NewFactory().
+			Balancer(reFastHttpFixture.Balancer(map[string][]string{"service": {stuck.Address(), fast.Address()}})).
+			Hedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxExtraLoad: 1}).
+			Bulkhead("service", BulkheadConfig{MaxInFlight: 1})
+		res, err := factory.Service("service").GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "fast", string(res.Body()))
+		assert.Equal(t, 1, factory.BulkheadStats()["service"].InFlight)
+		_, err = factory.Service("service").GET("/").Go()
+		assert.True(t, errors.Is(err, ErrBulkheadFull))
+
+		close(release)
+		eventually(t, func() bool {
+			return factory.BulkheadStats()["service"].InFlight == 0
+		})
+	})
+
+	t.Run("expect no hedging beyond extra load cap", func(t *testing.T) {
+		reset()
+		factory := NewFactory().
+			Balancer(reFastHttpFixture.Balancer(nodes)).
+			Hedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxExtraLoad: 0.5})
+		res, err := factory.Service("service").GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "slow", string(res.Body()))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&fastHits))
+	})
+
+	t.Run("expect non idempotent requests not to be hedged", func(t *testing.T) {
+		reset()
+		factory := NewFactory().
+			Balancer(reFastHttpFixture.Balancer(nodes)).
+			Hedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxExtraLoad: 1})
+		res, err := factory.Service("service").POST("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "slow", string(res.Body()))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&fastHits))
+	})
+
+	t.Run("expect idempotent requests to be hedged with the same key", func(t *testing.T) {
+		var mu sync.Mutex
+		var keys, bodies []string
+		record := func(ctx *fasthttp.RequestCtx) {
+			mu.Lock()
+			defer mu.Unlock()
+			keys = append(keys, string(ctx.Request.Header.Peek(HeaderIdempotencyKey)))
+			bodies = append(bodies, string(ctx.PostBody()))
+		}
+		slowPost := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			record(ctx)
+			time.Sleep(150 * time.Millisecond)
+			ctx.WriteString("slow")
+		})
+		defer slowPost.Finish()
+		fastPost := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			record(ctx)
+			ctx.WriteString("fast")
+		})
+		defer fastPost.Finish()
+		factory := NewFactory().
+			Balancer(reFastHttpFixture.Balancer(map[string][]string{"service": {slowPost.Address(), fastPost.Address()}})).
+			Hedging(HedgePolicy{Delay: 20 * time.Millisecond, MaxExtraLoad: 1})
+
+		res, err := factory.Service("service").POST("/").(Builder).
+			Idempotent("order-1").
+			Encoder(reFastHttpFixture.Encoder()).
+			ToEncode("ping").
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, "fast", string(res.Body()))
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{"order-1", "order-1"}, keys)
+		assert.Equal(t, []string{`"ping"`, `"ping"`}, bodies)
+	})
+
+	t.Run("expect delay to follow observed percentile", func(t *testing.T) {
+		h := NewFactory().Hedging(HedgePolicy{Delay: time.Second, Percentile: 0.95}).hedging
+		assert.Equal(t, time.Second, h.begin("service"))
+		for i := 1; i <= 100; i++ {
+			h.observe("service", time.Duration(i)*time.Millisecond)
+		}
+		assert.Equal(t, 96*time.Millisecond, h.begin("service"))
+	})
+}
@@ -0,0 +1,47 @@
+package refasthttp
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/valyala/fasthttp"
+)
+
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// Idempotent marks the request as safe to retry and sends it with an
+// Idempotency-Key header, key when given or a random UUID otherwise. The key
+// stays the same for every attempt of the call: connection retries, hedged
+// attempts and redirects.
+func (fhc *fastHttpClient) Idempotent(key ...string) Builder {
+	if len(key) > 0 && key[0] != "" {
+		fhc.req.Header.Set(HeaderIdempotencyKey, key[0])
+		return fhc
+	}
+	generated, err := newIdempotencyKey()
+	if err != nil {
+		fhc.err = err
+		return fhc
+	}
+	fhc.req.Header.Set(HeaderIdempotencyKey, generated)
+	return fhc
+}
+
+// newIdempotencyKey returns a random version 4 UUID.
+func newIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// retryIf extends the fasthttp rule, GET, HEAD and PUT are retried after
+// connection errors, to requests carrying an Idempotency-Key.
+func retryIf(req *fasthttp.Request) bool {
+	if req.Header.IsGet() || req.Header.IsHead() || req.Header.IsPut() {
+		return true
+	}
+	return len(req.Header.Peek(HeaderIdempotencyKey)) > 0
+}
@@ -0,0 +1,106 @@
+package refasthttp
+
+import (
+	"bufio"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// flakyServer breaks the first connection in the middle of the response
+// and answers on the following ones, it records the Idempotency-Key of every
+// request it reads.
+type flakyServer struct {
+	l    net.Listener
+	mu   sync.Mutex
+	keys []string
+}
+
+func newFlakyServer(t *testing.T) *flakyServer {
+	l, err := net.Listen("tcp4", "localhost:0")
+	require.NoError(t, err)
+	fs := &flakyServer{l: l}
+	go fs.serve()
+	return fs
+}
+
+func (fs *flakyServer) serve() {
+	for attempt := 0; ; attempt++ {
+		conn, err := fs.l.Accept()
+		if err != nil {
+			return
+		}
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err == nil {
+			fs.mu.Lock()
+			fs.keys = append(fs.keys, req.Header.Get(HeaderIdempotencyKey))
+			fs.mu.Unlock()
+			if attempt > 0 {
+				conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nOK"))
+			} else {
+				// fasthttp retries any request when the connection breaks
+				// before the first response byte, break it mid response
+				conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Len"))
+				conn.(*net.TCPConn).SetLinger(0)
+			}
+		}
+		conn.Close()
+	}
+}
+
+func (fs *flakyServer) Keys() []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]string(nil), fs.keys...)
+}
+
+func (fs *flakyServer) Address() string {
+	return "http://" + fs.l.Addr().String()
+}
+
+func TestFastHttpClient_Idempotent(t *testing.T) {
+	t.Run("expect retry with the same generated key", func(t *testing.T) {
+		fs := newFlakyServer(t)
+		defer fs.l.Close()
+
+		res, err := NewFactory().To(fs.Address()).POST("/").(Builder).Idempotent().Go()
+		require.NoError(t, err)
+		assert.Equal(t, "OK", string(res.Body()))
+		keys := fs.Keys()
+		require.Len(t, keys, 2)
+		assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), keys[0])
+		assert.Equal(t, keys[0], keys[1])
+	})
+
+	t.Run("expect given key to be sent", func(t *testing.T) {
+		fs := newFlakyServer(t)
+		defer fs.l.Close()
+
+		key := trifle.String()
+		_, err := NewFactory().To(fs.Address()).PATCH("/").(Builder).Idempotent(key).Go()
+		require.NoError(t, err)
+		assert.Equal(t, []string{key, key}, fs.Keys())
+	})
+
+	t.Run("expect no retry without key", func(t *testing.T) {
+		fs := newFlakyServer(t)
+		defer fs.l.Close()
+
+		_, err := NewFactory().To(fs.Address()).POST("/").Go()
+		assert.Error(t, err)
+		assert.Equal(t, []string{""}, fs.Keys())
+	})
+
+	t.Run("expect distinct keys per call", func(t *testing.T) {
+		first, err := newIdempotencyKey()
+		require.NoError(t, err)
+		second, err := newIdempotencyKey()
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+}
@@ -0,0 +1,105 @@
+package refasthttp
+
+import (
+	"fmt"
+	"github.com/remicro/api/logging"
+	"sync"
+	"time"
+)
+
+type recordedEntry struct {
+	Level   string
+	Message string
+	Fields  map[string]interface{}
+}
+
+type recordLogger struct {
+	mu      sync.Mutex
+	entries []recordedEntry
+}
+
+func (rl *recordLogger) Entries() []recordedEntry {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return append([]recordedEntry(nil), rl.entries...)
+}
+
+func (rl *recordLogger) entry(level string) logging.Entry {
+	return &recordEntry{
+		logger: rl,
+		entry:  recordedEntry{Level: level, Fields: map[string]interface{}{}},
+	}
+}
+
+func (rl *recordLogger) Info() logging.Entry {
+	return rl.entry("info")
+}
+
+func (rl *recordLogger) Error() logging.Entry {
+	return rl.entry("error")
+}
+
+func (rl *recordLogger) Debug() logging.Entry {
+	return rl.entry("debug")
+}
+
+func (rl *recordLogger) Warn() logging.Entry {
+	return rl.entry("warn")
+}
+
+func (rl *recordLogger) Critical() logging.Entry {
+	return rl.entry("critical")
+}
+
+type recordEntry struct {
+	logger *recordLogger
+	entry  recordedEntry
+}
+
+func (re *recordEntry) field(key string, value interface{}) logging.Entry {
+	re.entry.Fields[key] = value
+	return re
+}
+
+func (re *recordEntry) String(key, value string) logging.Entry {
+	return re.field(key, value)
+}
+
+func (re *recordEntry) Int(key string, value int) logging.Entry {
+	return re.field(key, value)
+}
+
+func (re *recordEntry) Err(err error) logging.Entry {
+	return re.field("error", err)
+}
+
+func (re *recordEntry) Bool(key string, value bool) logging.Entry {
+	return re.field(key, value)
+}
+
+func (re *recordEntry) Time(key string, value time.Time) logging.Entry {
+	return re.field(key, value)
+}
+
+func (re *recordEntry) Duration(key string, duration time.Duration) logging.Entry {
+	return re.field(key, duration)
+}
+
+func (re *recordEntry) Float64(key string, value float64) logging.Entry {
+	return re.field(key, value)
+}
+
+func (re *recordEntry) Uint64(key string, value uint64) logging.Entry {
+	return re.field(key, value)
+}
+
+func (re *recordEntry) Logf(message string, args ...interface{}) {
+	re.Log(fmt.Sprintf(message, args...))
+}
+
+func (re *recordEntry) Log(message string) {
+	re.entry.Message = message
+	re.logger.mu.Lock()
+	re.logger.entries = append(re.logger.entries, re.entry)
+	re.logger.mu.Unlock()
+}
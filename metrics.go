@@ -0,0 +1,68 @@
+package refasthttp
+
+import (
+	"github.com/remicro/api/net/rehttp"
+	"strconv"
+	"time"
+)
+
+// CallLabels describe a call to Metrics. Path is the path template of calls
+// with path params and "other" for the rest, raw paths would make a label
+// per id. StatusClass is "2xx", "4xx" and so on, or "error" when no response
+// was received. StatusClass is empty for Started.
+type CallLabels struct {
+	Service     string
+	Method      string
+	Path        string
+	StatusClass string
+}
+
+// CallResult is the outcome of a call reported to Metrics.
+type CallResult struct {
+	Latency       time.Duration
+	RequestBytes  int
+	ResponseBytes int
+	Timing        Timing
+	Err           error
+}
+
+// Metrics is notified about every call of a factory.
+type Metrics interface {
+	Started(labels CallLabels)
+	Finished(labels CallLabels, result CallResult)
+}
+
+func (f *Factory) Metrics(metrics Metrics) *Factory {
+	f.metrics = metrics
+	return f
+}
+
+const otherPath = "other"
+
+func (fhc *fastHttpClient) callLabels() CallLabels {
+	path := otherPath
+	if len(fhc.pathParams) > 0 {
+		path = fhc.pathTemplate()
+	}
+	return CallLabels{
+		Service: fhc.service,
+		Method:  string(fhc.req.Header.Method()),
+		Path:    path,
+	}
+}
+
+func (fhc *fastHttpClient) recordCall(metrics Metrics, labels CallLabels, started time.Time, response rehttp.Response, err error) {
+	result := CallResult{
+		Latency:      time.Since(started),
+		RequestBytes: len(fhc.req.Body()),
+		Timing:       fhc.timing,
+		Err:          err,
+	}
+	result.Timing.Total = result.Latency
+	labels.StatusClass = "error"
+	if response != nil {
+		labels.StatusClass = strconv.Itoa(response.Status()/100) + "xx"
+		result.ResponseBytes = len(response.Body())
+	}
+	metrics.Finished(labels, result)
+}
@@ -0,0 +1,68 @@
+package refasthttp
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PathParam fills the {key} placeholder of the path given to GET, POST and
+// the other methods. Access logs report the path with its placeholders.
+func (fhc *fastHttpClient) PathParam(key, value string) Builder {
+	if fhc.pathParams == nil {
+		fhc.pathParams = make(map[string]string)
+	}
+	fhc.pathParams[key] = value
+	return fhc
+}
+
+// expandPath keeps the path template and replaces its placeholders in a
+// single pass, so a value can't add placeholders of its own. Values are
+// escaped to one path segment and the path is sent as built, since
+// normalizing would decode %2F and resolve dot segments.
+func (fhc *fastHttpClient) expandPath() {
+	fhc.template = string(fhc.uri.Path())
+	if len(fhc.pathParams) == 0 {
+		return
+	}
+	var path strings.Builder
+	rest := fhc.template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		path.WriteString(escapePath(rest[:start]))
+		if value, ok := fhc.pathParams[rest[start+1:end]]; ok {
+			path.WriteString(escapeSegment(value))
+		} else {
+			path.WriteString(escapePath(rest[start : end+1]))
+		}
+		rest = rest[end+1:]
+	}
+	path.WriteString(escapePath(rest))
+	fhc.uri.SetPath(path.String())
+	fhc.uri.DisablePathNormalizing = true
+}
+
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+func escapeSegment(value string) string {
+	if value == "." || value == ".." {
+		return strings.Replace(value, ".", "%2E", -1)
+	}
+	return url.PathEscape(value)
+}
+
+func (fhc *fastHttpClient) pathTemplate() string {
+	if fhc.template != "" {
+		return fhc.template
+	}
+	return string(fhc.uri.Path())
+}
@@ -0,0 +1,43 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+func TestFastHttpClient_PathParam(t *testing.T) {
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Write(ctx.RequestURI())
+	})
+	defer fx.Finish()
+	send := func(t *testing.T, template string, params ...string) string {
+		builder := New().Address(fx.Address()).GET(template).(Builder)
+		for i := 0; i < len(params); i += 2 {
+			builder.PathParam(params[i], params[i+1])
+		}
+		res, err := builder.Go()
+		require.NoError(t, err)
+		return string(res.Body())
+	}
+
+	t.Run("expect traversal to stay in its segment", func(t *testing.T) {
+		assert.Equal(t, "/users/..%2Fadmin/orders", send(t, "/users/{id}/orders", "id", "../admin"))
+		assert.Equal(t, "/users/%2E%2E", send(t, "/users/{id}", "id", ".."))
+	})
+
+	t.Run("expect slashes to be escaped", func(t *testing.T) {
+		assert.Equal(t, "/files/a%2Fb%252Fc%20d", send(t, "/files/{name}", "name", "a/b%2Fc d"))
+	})
+
+	t.Run("expect values not to be expanded again", func(t *testing.T) {
+		assert.Equal(t, "/%7Bb%7D/x", send(t, "/{a}/{b}", "a", "{b}", "b", "x"))
+		assert.Equal(t, "/1/%7Bb%7D", send(t, "/{a}/{b}", "a", "1"))
+	})
+
+	t.Run("expect paths without params to be normalized", func(t *testing.T) {
+		assert.Equal(t, "/b%20c", send(t, "/a/../b c"))
+	})
+}
@@ -0,0 +1,209 @@
+package refasthttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/remicro/api/logging"
+	"github.com/valyala/fasthttp"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const pinPrefix = "sha256/"
+
+var (
+	ErrPinMismatch = errors.New("server certificate doesn't match any pin")
+)
+
+// Pinning verifies server certificates against SPKI SHA-256 pins in the TLS
+// handshake. Pins are registered per address or per balancer service and are
+// matched by the host dialed, whatever server name the TLS config sends.
+// Several pins per target allow backup keys during rotation.
+type Pinning struct {
+	mu           sync.RWMutex
+	hosts        map[string]map[string]bool
+	services     map[string]map[string]bool
+	serviceHosts map[string]string
+	reportOnly   bool
+	logger       logging.Logger
+}
+
+func NewPinning() *Pinning {
+	return &Pinning{
+		hosts:        make(map[string]map[string]bool),
+		services:     make(map[string]map[string]bool),
+		serviceHosts: make(map[string]string),
+		logger:       dummyLogger{},
+	}
+}
+
+// Address pins the host of address to the given base64 encoded SPKI hashes,
+// with or without the "sha256/" prefix.
+func (p *Pinning) Address(address string, pins ...string) *Pinning {
+	p.hosts[pinHost(address)] = pinSet(p.hosts[pinHost(address)], pins)
+	return p
+}
+
+// Service pins every node the balancer returns for the service.
+func (p *Pinning) Service(name string, pins ...string) *Pinning {
+	p.services[name] = pinSet(p.services[name], pins)
+	return p
+}
+
+// ReportOnly logs mismatches as warnings instead of failing the handshake.
+func (p *Pinning) ReportOnly(reportOnly bool) *Pinning {
+	p.reportOnly = reportOnly
+	return p
+}
+
+func (p *Pinning) Logger(logger logging.Logger) *Pinning {
+	p.logger = logger
+	return p
+}
+
+// pinnedClient is the client of https requests with pinning, see dial.
+type pinnedClient struct {
+	mu     sync.Mutex
+	client *fasthttp.Client
+}
+
+func (f *Factory) Pinning(pinning *Pinning) *Factory {
+	f.pinning = pinning
+	return f
+}
+
+// SPKIHash returns the pin of a certificate in the "sha256/<base64>" form.
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (p *Pinning) bindService(address, name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.services[name]; ok {
+		p.serviceHosts[pinHost(address)] = name
+	}
+}
+
+func (p *Pinning) pins(host string) (pins map[string]bool, ok bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if pins, ok = p.hosts[host]; ok {
+		return
+	}
+	if name, bound := p.serviceHosts[host]; bound {
+		pins, ok = p.services[name]
+	}
+	return
+}
+
+// dial opens TLS connections to the hosts dialed by dial, verifying the pins
+// of each host. fasthttp uses connections which are TLS already as they
+// are, so the host is known to the check rather than guessed from the
+// server name or the certificate.
+// pinned tells whether the request of fhc is sent over TLS with pinning.
+func (fhc *fastHttpClient) pinned() bool {
+	return fhc.factory.pinning != nil && bytes.EqualFold(fhc.uri.Scheme(), []byte("https"))
+}
+
+func (p *Pinning) dial(dial fasthttp.DialFunc, config *tls.Config) fasthttp.DialFunc {
+	var mu sync.Mutex
+	configs := make(map[string]*tls.Config)
+	return func(addr string) (net.Conn, error) {
+		mu.Lock()
+		hostConfig, ok := configs[addr]
+		if !ok {
+			hostConfig = p.config(config, addr)
+			configs[addr] = hostConfig
+		}
+		mu.Unlock()
+		conn, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		return tls.Client(conn, hostConfig), nil
+	}
+}
+
+// config derives the TLS config of addr the way fasthttp does and checks the
+// pins of its host once the handshake is done.
+func (p *Pinning) config(config *tls.Config, addr string) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	if config.ClientSessionCache == nil {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	host := pinHost(addr)
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		return p.verify(host, state)
+	}
+	return config
+}
+
+func (p *Pinning) verify(host string, state tls.ConnectionState) error {
+	pins, ok := p.pins(host)
+	if !ok {
+		return nil
+	}
+	// the server picks the peer certificates, only the verified chains prove
+	// a pinned key, or the leaf when verification is skipped
+	var certificates []*x509.Certificate
+	for _, chain := range state.VerifiedChains {
+		certificates = append(certificates, chain...)
+	}
+	if len(state.VerifiedChains) == 0 && len(state.PeerCertificates) > 0 {
+		certificates = state.PeerCertificates[:1]
+	}
+	for _, cert := range certificates {
+		if pins[SPKIHash(cert)] {
+			return nil
+		}
+	}
+	if p.reportOnly {
+		entry := p.logger.Warn().String("host", host)
+		if len(state.PeerCertificates) > 0 {
+			entry = entry.String("pin", SPKIHash(state.PeerCertificates[0]))
+		}
+		entry.Log("server certificate doesn't match any pin")
+		return nil
+	}
+	return fmt.Errorf("%s: %w", host, ErrPinMismatch)
+}
+
+func pinSet(set map[string]bool, pins []string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool)
+	}
+	for _, pin := range pins {
+		if !strings.HasPrefix(pin, pinPrefix) {
+			pin = pinPrefix + pin
+		}
+		set[pin] = true
+	}
+	return set
+}
+
+func pinHost(address string) string {
+	host := address
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,163 @@
+package refasthttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+func TestPinning(t *testing.T) {
+	certs := reFastHttpFixture.NewCertificates(t)
+	leaf, err := x509.ParseCertificate(certs.ServerCert.Certificate[0])
+	require.NoError(t, err)
+	serverPin := SPKIHash(leaf)
+	otherCert, err := x509.ParseCertificate(reFastHttpFixture.NewCertificates(t).ServerCert.Certificate[0])
+	require.NoError(t, err)
+	otherPin := SPKIHash(otherCert)
+
+	config, err := NewTLS().RootCA(certs.CAFile).Build()
+	require.NoError(t, err)
+
+	fx := reFastHttpFixture.NewTLS(t, certs, tls.NoClientCert, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetConnectionClose()
+	})
+	defer fx.Finish()
+
+	t.Run("expect matching pin to pass", func(t *testing.T) {
+		factory := NewFactory().
+			TLS(config).
+			Pinning(NewPinning().Address(fx.Address(), serverPin))
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status())
+	})
+
+	t.Run("expect mismatching pin to fail the handshake", func(t *testing.T) {
+		factory := NewFactory().
+			Pinning(NewPinning().Address(fx.Address(), otherPin)).
+			TLS(config)
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrPinMismatch))
+		assert.Nil(t, res)
+	})
+
+	t.Run("expect pinned certificate outside the verified chain to fail", func(t *testing.T) {
+		other := reFastHttpFixture.NewCertificates(t)
+		forged := *certs
+		forged.ServerCert.Certificate = append(append([][]byte(nil), certs.ServerCert.Certificate...), other.ServerCert.Certificate[0])
+		forgedFx := reFastHttpFixture.NewTLS(t, &forged, tls.NoClientCert, func(ctx *fasthttp.RequestCtx) {})
+		defer forgedFx.Finish()
+
+		factory := NewFactory().
+			TLS(config).
+			Pinning(NewPinning().Address(forgedFx.Address(), SPKIHash(mustParseCertificate(t, other.ServerCert.Certificate[0]))))
+		_, err := factory.To(forgedFx.Address()).GET("/").Go()
+		assert.True(t, errors.Is(err, ErrPinMismatch))
+	})
+
+	t.Run("expect pins of the dialed host whatever the server name", func(t *testing.T) {
+		named, err := NewTLS().RootCA(certs.CAFile).ServerName("localhost").Build()
+		require.NoError(t, err)
+		factory := NewFactory().
+			TLS(named).
+			Pinning(NewPinning().Address(fx.Address(), otherPin))
+		_, err = factory.To(fx.Address()).GET("/").Go()
+		assert.True(t, errors.Is(err, ErrPinMismatch))
+
+		factory = NewFactory().
+			TLS(&tls.Config{InsecureSkipVerify: true}).
+			Pinning(NewPinning().Address(fx.Address(), otherPin))
+		_, err = factory.To(fx.Address()).GET("/").Go()
+		assert.True(t, errors.Is(err, ErrPinMismatch))
+
+		factory = NewFactory().
+			TLS(named).
+			Pinning(NewPinning().Address(fx.Address(), serverPin))
+		_, err = factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+	})
+
+	t.Run("expect pins through proxies and pipelines", func(t *testing.T) {
+		proxy := reFastHttpFixture.NewHTTPProxy(t, "", "")
+		defer proxy.Finish()
+		factory := NewFactory().
+			TLS(config).
+			Proxy(ProxyConfig{HTTPSProxy: proxy.URL("", "")}).
+			Pinning(NewPinning().Address(fx.Address(), otherPin))
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		assert.True(t, errors.Is(err, ErrPinMismatch))
+		assert.NotEmpty(t, proxy.Targets())
+
+		factory = NewFactory().
+			TLS(config).
+			Pipeline(PipelineConfig{}).
+			Pinning(NewPinning().Address(fx.Address(), otherPin))
+		_, err = factory.To(fx.Address()).GET("/").Go()
+		assert.Error(t, err)
+
+		factory = NewFactory().
+			TLS(config).
+			Pipeline(PipelineConfig{}).
+			Pinning(NewPinning().Address(fx.Address(), serverPin))
+		_, err = factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+	})
+
+	t.Run("expect backup pin to pass during rotation", func(t *testing.T) {
+		factory := NewFactory().
+			TLS(config).
+			Pinning(NewPinning().Address(fx.Address(), otherPin, serverPin))
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+	})
+
+	t.Run("expect report only mode to log mismatch", func(t *testing.T) {
+		logger := &recordLogger{}
+		factory := NewFactory().
+			TLS(config).
+			Pinning(NewPinning().Address(fx.Address(), otherPin).ReportOnly(true).Logger(logger))
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		entries := logger.Entries()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "warn", entries[0].Level)
+		assert.Equal(t, "127.0.0.1", entries[0].Fields["host"])
+		assert.Equal(t, serverPin, entries[0].Fields["pin"])
+	})
+
+	t.Run("expect service pins to apply to balancer nodes", func(t *testing.T) {
+		factory := NewFactory().
+			TLS(config).
+			Balancer(reFastHttpFixture.Balancer(map[string][]string{"partner": {fx.Address()}})).
+			Pinning(NewPinning().Service("partner", otherPin))
+		_, err := factory.Service("partner").GET("/").Go()
+		assert.True(t, errors.Is(err, ErrPinMismatch))
+
+		factory = NewFactory().
+			TLS(config).
+			Balancer(reFastHttpFixture.Balancer(map[string][]string{"partner": {fx.Address()}})).
+			Pinning(NewPinning().Service("partner", serverPin))
+		_, err = factory.Service("partner").GET("/").Go()
+		require.NoError(t, err)
+	})
+
+	t.Run("expect unpinned hosts to pass", func(t *testing.T) {
+		factory := NewFactory().
+			TLS(config).
+			Pinning(NewPinning().Address("https://example.com", otherPin))
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+	})
+}
+
+func mustParseCertificate(t *testing.T, der []byte) *x509.Certificate {
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
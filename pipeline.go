@@ -0,0 +1,88 @@
+package refasthttp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/remicro/api/logging"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"time"
+)
+
+// PipelineConfig switches a Factory to HTTP pipelining: requests to the
+// same host are written back to back on a few connections without waiting
+// for the responses. Zero values keep the fasthttp defaults, a single
+// connection and 1024 pending requests per host.
+type PipelineConfig struct {
+	MaxConns           int
+	MaxPendingRequests int
+	MaxBatchDelay      time.Duration
+}
+
+// Pipeline makes requests of the factory go through pipelined connections.
+// Unix sockets and proxied requests keep using the regular pools. When the
+// queue of a host is full Go returns a *PoolExhaustedError.
+func (f *Factory) Pipeline(config PipelineConfig) *Factory {
+	f.pipeline = &config
+	return f
+}
+
+// pipelines keeps one pipeline client per "host:port".
+type pipelines struct {
+	mu      sync.Mutex
+	clients map[string]*fasthttp.PipelineClient
+}
+
+func (f *Factory) pipelineClient(fhc *fastHttpClient) *fasthttp.PipelineClient {
+	host := fhc.poolHost()
+	isTLS := bytes.EqualFold(fhc.uri.Scheme(), []byte("https"))
+	key := host
+	if isTLS {
+		key = "https://" + host
+	}
+
+	f.pipelines.mu.Lock()
+	defer f.pipelines.mu.Unlock()
+	if client, ok := f.pipelines.clients[key]; ok {
+		return client
+	}
+	if f.pipelines.clients == nil {
+		f.pipelines.clients = make(map[string]*fasthttp.PipelineClient)
+	}
+	src := f.client
+	dial := src.Dial
+	if isTLS && f.pinning != nil {
+		dial = f.pinning.dial(dial, f.tls)
+	}
+	client := &fasthttp.PipelineClient{
+		Addr:                          host,
+		Name:                          src.Name,
+		NoDefaultUserAgentHeader:      src.NoDefaultUserAgentHeader,
+		MaxConns:                      f.pipeline.MaxConns,
+		MaxPendingRequests:            f.pipeline.MaxPendingRequests,
+		MaxBatchDelay:                 f.pipeline.MaxBatchDelay,
+		Dial:                          dial,
+		DisableHeaderNamesNormalizing: src.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        src.DisablePathNormalizing,
+		IsTLS:                         isTLS,
+		TLSConfig:                     src.TLSConfig,
+		MaxIdleConnDuration:           src.MaxIdleConnDuration,
+		ReadBufferSize:                src.ReadBufferSize,
+		WriteBufferSize:               src.WriteBufferSize,
+		ReadTimeout:                   src.ReadTimeout,
+		WriteTimeout:                  src.WriteTimeout,
+		Logger:                        pipelineLogger{logger: f.logger},
+	}
+	f.pipelines.clients[key] = client
+	return client
+}
+
+// pipelineLogger forwards the connection errors fasthttp reports to the
+// factory logger.
+type pipelineLogger struct {
+	logger logging.Logger
+}
+
+func (pl pipelineLogger) Printf(format string, args ...interface{}) {
+	pl.logger.Debug().Log(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,91 @@
+package refasthttp
+
+import (
+	"errors"
+	"github.com/remicro/api/net/rehttp"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestFactory_Pipeline(t *testing.T) {
+	t.Run("expect requests to share a single connection", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			var req Object
+			assert.NoError(t, reFastHttpFixture.Decoder().Decode(&req, ctx.PostBody()))
+			data, err := reFastHttpFixture.Encoder().Encode(&req)
+			assert.NoError(t, err)
+			ctx.Response.Header.SetContentType("application/json")
+			ctx.Write(data)
+		})
+		defer fx.Finish()
+
+		factory := NewFactory().Pipeline(PipelineConfig{MaxConns: 1})
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(label string) {
+				defer wg.Done()
+				var result Object
+				res, err := factory.To(fx.Address()).
+					POST("/").
+					Encoder(reFastHttpFixture.Encoder()).
+					ToEncode(Object{Label: label}).
+					Decoder(reFastHttpFixture.Decoder()).
+					ToDecode(&result).
+					DecodeType(rehttp.ContentTypeJson).
+					Go()
+				if assert.NoError(t, err) {
+					assert.Equal(t, fasthttp.StatusOK, res.Status())
+					assert.Equal(t, label, result.Label)
+				}
+			}(strconv.Itoa(i))
+		}
+		wg.Wait()
+		assert.Equal(t, HostStats{Open: 1, Idle: 1}, factory.Stats()[fixtureHost(fx)])
+	})
+
+	t.Run("expect pool exhausted error when queue overflows", func(t *testing.T) {
+		release := make(chan struct{})
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			<-release
+		})
+		defer fx.Finish()
+
+		factory := NewFactory().Pipeline(PipelineConfig{MaxConns: 1, MaxPendingRequests: 1})
+		var mu sync.Mutex
+		var overflows, succeeded int
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := factory.To(fx.Address()).GET("/").Go()
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					succeeded++
+				case errors.Is(err, ErrPoolExhausted):
+					assert.True(t, errors.Is(err, fasthttp.ErrPipelineOverflow))
+					overflows++
+				default:
+					assert.NoError(t, err)
+				}
+			}()
+		}
+		eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return overflows > 0
+		})
+		close(release)
+		wg.Wait()
+		require.True(t, succeeded > 0)
+		assert.Equal(t, 10, overflows+succeeded)
+	})
+}
@@ -0,0 +1,245 @@
+package refasthttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPoolExhausted = errors.New("connection pool exhausted")
+)
+
+// PoolConfig tunes the connection pools of a Factory, zero values keep the
+// fasthttp defaults.
+type PoolConfig struct {
+	MaxConnsPerHost     int
+	MaxIdleConnDuration time.Duration
+	MaxConnDuration     time.Duration
+	// MaxConnWaitTimeout is how long a request waits for a free connection
+	// once MaxConnsPerHost is reached, zero fails right away.
+	MaxConnWaitTimeout time.Duration
+}
+
+// PoolExhaustedError is returned when no connection to Host became free
+// within Wait or the pipeline queue of Host is full. It matches both
+// ErrPoolExhausted and the fasthttp error, ErrNoFreeConns or
+// ErrPipelineOverflow.
+type PoolExhaustedError struct {
+	Host  string
+	Wait  time.Duration
+	cause error
+}
+
+func (e *PoolExhaustedError) Error() string {
+	return fmt.Sprintf("%s: %s after %s", e.Host, ErrPoolExhausted, e.Wait)
+}
+
+func (e *PoolExhaustedError) Is(target error) bool {
+	return target == ErrPoolExhausted
+}
+
+func (e *PoolExhaustedError) Unwrap() error {
+	return e.cause
+}
+
+// HostStats is a snapshot of the connections to a single host. Waiting
+// counts requests queued for a connection.
+type HostStats struct {
+	Open    int
+	Idle    int
+	InUse   int
+	Waiting int
+}
+
+func (f *Factory) Pool(config PoolConfig) *Factory {
+	f.client.MaxConnsPerHost = config.MaxConnsPerHost
+	f.client.MaxIdleConnDuration = config.MaxIdleConnDuration
+	f.client.MaxConnDuration = config.MaxConnDuration
+	f.client.MaxConnWaitTimeout = config.MaxConnWaitTimeout
+	return f
+}
+
+// Stats reports the connections of every host the factory is connected to
+// or has requests in flight for, keyed by "host:port" or socket path.
+func (f *Factory) Stats() map[string]HostStats {
+	return f.pool.stats()
+}
+
+// pool counts the connections dialed by the clients of a factory and the
+// requests in flight per host, fasthttp does not expose its idle lists.
+type pool struct {
+	mu    sync.Mutex
+	hosts map[string]*hostCounters
+	conns map[string]*trackedConn
+	// closed keeps the last connections closed in conns, fasthttp closes a
+	// connection the response asks to close before returning it.
+	closed []*trackedConn
+}
+
+const closedConns = 64
+
+type hostCounters struct {
+	open     int
+	inFlight int
+}
+
+func (p *pool) counters(host string) *hostCounters {
+	if p.hosts == nil {
+		p.hosts = make(map[string]*hostCounters)
+	}
+	counters, ok := p.hosts[host]
+	if !ok {
+		counters = &hostCounters{}
+		p.hosts[host] = counters
+	}
+	return counters
+}
+
+func (p *pool) update(host string, open, inFlight int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counters := p.counters(host)
+	counters.open += open
+	counters.inFlight += inFlight
+	if counters.open == 0 && counters.inFlight == 0 {
+		delete(p.hosts, host)
+	}
+}
+
+func (p *pool) stats() map[string]HostStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]HostStats, len(p.hosts))
+	for host, counters := range p.hosts {
+		inUse := counters.inFlight
+		if inUse > counters.open {
+			inUse = counters.open
+		}
+		stats[host] = HostStats{
+			Open:    counters.open,
+			Idle:    counters.open - inUse,
+			InUse:   inUse,
+			Waiting: counters.inFlight - inUse,
+		}
+	}
+	return stats
+}
+
+// track counts the connections opened by dial until they are closed and
+// instruments them for Timing, they are found again by local address.
+func (p *pool) track(dial timedDial) fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		dialStarted := time.Now()
+		conn, resolving, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		tc := &trackedConn{
+			Conn:        conn,
+			dialStarted: dialStarted,
+			dialed:      time.Now(),
+			resolving:   resolving,
+		}
+		tc.key = connKey(conn.LocalAddr())
+		tc.release = func() {
+			p.update(addr, -1, 0)
+			p.forget(tc)
+		}
+		p.update(addr, 1, 0)
+		p.remember(tc)
+		return tc, nil
+	}
+}
+
+// connKey identifies a connection by its local address, empty for unnamed
+// addresses such as the client end of unix sockets.
+func connKey(addr net.Addr) string {
+	if addr == nil || addr.String() == "" || addr.String() == "@" {
+		return ""
+	}
+	return addr.Network() + "/" + addr.String()
+}
+
+func (p *pool) remember(tc *trackedConn) {
+	if tc.key == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conns == nil {
+		p.conns = make(map[string]*trackedConn)
+	}
+	p.conns[tc.key] = tc
+}
+
+func (p *pool) forget(tc *trackedConn) {
+	if tc.key == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = append(p.closed, tc)
+	if len(p.closed) <= closedConns {
+		return
+	}
+	oldest := p.closed[0]
+	p.closed[0] = nil
+	p.closed = p.closed[1:]
+	if p.conns[oldest.key] == oldest {
+		delete(p.conns, oldest.key)
+	}
+}
+
+// conn returns the tracked connection with the local address addr.
+func (p *pool) conn(addr net.Addr) *trackedConn {
+	key := connKey(addr)
+	if key == "" {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conns[key]
+}
+
+type trackedConn struct {
+	net.Conn
+	key     string
+	once    sync.Once
+	release func()
+
+	dialStarted time.Time
+	dialed      time.Time
+	resolving   time.Duration
+
+	mu        sync.Mutex
+	reading   bool
+	exchanges []exchange
+}
+
+func (tc *trackedConn) Close() error {
+	tc.once.Do(tc.release)
+	return tc.Conn.Close()
+}
+
+// poolHost returns the key the transport of fhc dials, fasthttp adds the
+// default port of the scheme when the host has none.
+func (fhc *fastHttpClient) poolHost() string {
+	if fhc.socket != "" {
+		return fhc.socket
+	}
+	host := string(fhc.uri.Host())
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if bytes.EqualFold(fhc.uri.Scheme(), []byte("https")) {
+		return net.JoinHostPort(host, "443")
+	}
+	return net.JoinHostPort(host, "80")
+}
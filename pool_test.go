@@ -0,0 +1,75 @@
+package refasthttp
+
+import (
+	"errors"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+	"time"
+)
+
+func TestFactory_Pool(t *testing.T) {
+	t.Run("expect settings to be applied to the client", func(t *testing.T) {
+		config := PoolConfig{
+			MaxConnsPerHost:     3,
+			MaxIdleConnDuration: time.Second,
+			MaxConnDuration:     time.Minute,
+			MaxConnWaitTimeout:  time.Millisecond,
+		}
+		factory := NewFactory().Pool(config)
+		assert.Equal(t, 3, factory.client.MaxConnsPerHost)
+		assert.Equal(t, time.Second, factory.client.MaxIdleConnDuration)
+		assert.Equal(t, time.Minute, factory.client.MaxConnDuration)
+		assert.Equal(t, time.Millisecond, factory.client.MaxConnWaitTimeout)
+	})
+
+	t.Run("expect typed error and stats when pool is exhausted", func(t *testing.T) {
+		release := make(chan struct{})
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			<-release
+			ctx.WriteString("OK")
+		})
+		defer fx.Finish()
+		host := fixtureHost(fx)
+
+		factory := NewFactory().Pool(PoolConfig{
+			MaxConnsPerHost:    1,
+			MaxConnWaitTimeout: 20 * time.Millisecond,
+		})
+		done := make(chan error)
+		go func() {
+			_, err := factory.To(fx.Address()).GET("/").Go()
+			done <- err
+		}()
+		eventually(t, func() bool {
+			return factory.Stats()[host] == HostStats{Open: 1, InUse: 1}
+		})
+
+		started := time.Now()
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		assert.Nil(t, res)
+		assert.True(t, errors.Is(err, ErrPoolExhausted))
+		assert.True(t, errors.Is(err, fasthttp.ErrNoFreeConns))
+		var exhausted *PoolExhaustedError
+		require.True(t, errors.As(err, &exhausted))
+		assert.Equal(t, host, exhausted.Host)
+		assert.True(t, time.Since(started) >= 20*time.Millisecond)
+
+		close(release)
+		require.NoError(t, <-done)
+		assert.Equal(t, HostStats{Open: 1, Idle: 1}, factory.Stats()[host])
+	})
+
+	t.Run("expect default port for hosts without one", func(t *testing.T) {
+		poolHost := func(address string) string {
+			return NewFactory().To(address).(*fastHttpClient).poolHost()
+		}
+		assert.Equal(t, "[::1]:80", poolHost("http://[::1]"))
+		assert.Equal(t, "[::1]:443", poolHost("https://[::1]/"))
+		assert.Equal(t, "[::1]:8080", poolHost("http://[::1]:8080"))
+		assert.Equal(t, "example.com:443", poolHost("https://example.com"))
+		assert.Equal(t, "example.com:8443", poolHost("https://example.com:8443"))
+	})
+}
@@ -0,0 +1,243 @@
+package refasthttp
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the latency
+// histogram of PrometheusMetrics.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics keeps RED metrics of the calls of a factory in memory
+// and writes them in the Prometheus text exposition format:
+//
+//	http_client_requests_total                 counter   service, method, path, status_class
+//	http_client_request_duration_seconds       histogram service, method, path, status_class
+//	http_client_requests_in_flight             gauge     service, method, path
+//	http_client_request_bytes_total            counter   service, method, path
+//	http_client_response_bytes_total           counter   service, method, path
+//	http_client_errors_total                   counter   service, method, path
+//
+// Errors count failed calls and 5xx responses.
+type PrometheusMetrics struct {
+	prefix  string
+	buckets []float64
+
+	mu       sync.Mutex
+	calls    map[CallLabels]*callSeries
+	inFlight map[CallLabels]float64
+	traffic  map[CallLabels]*trafficSeries
+}
+
+type callSeries struct {
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+type trafficSeries struct {
+	requestBytes  uint64
+	responseBytes uint64
+	errors        uint64
+}
+
+// NewPrometheusMetrics prefixes the metric names with namespace and "_"
+// unless namespace is empty. buckets default to DefaultLatencyBuckets.
+func NewPrometheusMetrics(namespace string, buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	prefix := ""
+	if namespace != "" {
+		prefix = namespace + "_"
+	}
+	return &PrometheusMetrics{
+		prefix:   prefix,
+		buckets:  buckets,
+		calls:    make(map[CallLabels]*callSeries),
+		inFlight: make(map[CallLabels]float64),
+		traffic:  make(map[CallLabels]*trafficSeries),
+	}
+}
+
+func (pm *PrometheusMetrics) Started(labels CallLabels) {
+	labels.StatusClass = ""
+	pm.mu.Lock()
+	pm.inFlight[labels]++
+	pm.mu.Unlock()
+}
+
+func (pm *PrometheusMetrics) Finished(labels CallLabels, result CallResult) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	calls, ok := pm.calls[labels]
+	if !ok {
+		calls = &callSeries{buckets: make([]uint64, len(pm.buckets))}
+		pm.calls[labels] = calls
+	}
+	seconds := result.Latency.Seconds()
+	calls.count++
+	calls.sum += seconds
+	for i, bound := range pm.buckets {
+		if seconds <= bound {
+			calls.buckets[i]++
+		}
+	}
+
+	statusClass := labels.StatusClass
+	labels.StatusClass = ""
+	pm.inFlight[labels]--
+	traffic, ok := pm.traffic[labels]
+	if !ok {
+		traffic = &trafficSeries{}
+		pm.traffic[labels] = traffic
+	}
+	traffic.requestBytes += uint64(result.RequestBytes)
+	traffic.responseBytes += uint64(result.ResponseBytes)
+	if result.Err != nil || statusClass == "5xx" {
+		traffic.errors++
+	}
+}
+
+// WriteTo writes every metric in the Prometheus text format.
+func (pm *PrometheusMetrics) WriteTo(w io.Writer) (n int64, err error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	calls := sortedLabels(len(pm.calls), func(add func(CallLabels)) {
+		for labels := range pm.calls {
+			add(labels)
+		}
+	})
+	pm.header(cw, "requests_total", "counter", "Outgoing HTTP calls.")
+	for _, labels := range calls {
+		pm.sample(cw, "requests_total", formatLabels(labels, true), float64(pm.calls[labels].count))
+	}
+	pm.header(cw, "request_duration_seconds", "histogram", "Latency of outgoing HTTP calls.")
+	for _, labels := range calls {
+		series := pm.calls[labels]
+		base := formatLabels(labels, true)
+		for i, bound := range pm.buckets {
+			le := base + `,le="` + formatFloat(bound) + `"`
+			pm.sample(cw, "request_duration_seconds_bucket", le, float64(series.buckets[i]))
+		}
+		pm.sample(cw, "request_duration_seconds_bucket", base+`,le="+Inf"`, float64(series.count))
+		pm.sample(cw, "request_duration_seconds_sum", base, series.sum)
+		pm.sample(cw, "request_duration_seconds_count", base, float64(series.count))
+	}
+
+	inFlight := sortedLabels(len(pm.inFlight), func(add func(CallLabels)) {
+		for labels := range pm.inFlight {
+			add(labels)
+		}
+	})
+	pm.header(cw, "requests_in_flight", "gauge", "Outgoing HTTP calls in flight.")
+	for _, labels := range inFlight {
+		pm.sample(cw, "requests_in_flight", formatLabels(labels, false), pm.inFlight[labels])
+	}
+
+	traffic := sortedLabels(len(pm.traffic), func(add func(CallLabels)) {
+		for labels := range pm.traffic {
+			add(labels)
+		}
+	})
+	pm.header(cw, "request_bytes_total", "counter", "Request body bytes sent.")
+	for _, labels := range traffic {
+		pm.sample(cw, "request_bytes_total", formatLabels(labels, false), float64(pm.traffic[labels].requestBytes))
+	}
+	pm.header(cw, "response_bytes_total", "counter", "Response body bytes received.")
+	for _, labels := range traffic {
+		pm.sample(cw, "response_bytes_total", formatLabels(labels, false), float64(pm.traffic[labels].responseBytes))
+	}
+	pm.header(cw, "errors_total", "counter", "Failed outgoing HTTP calls and 5xx responses.")
+	for _, labels := range traffic {
+		pm.sample(cw, "errors_total", formatLabels(labels, false), float64(pm.traffic[labels].errors))
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// Handler serves the metrics, e.g. on /metrics of a fasthttp server.
+func (pm *PrometheusMetrics) Handler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain; version=0.0.4; charset=utf-8")
+		pm.WriteTo(ctx)
+	}
+}
+
+func (pm *PrometheusMetrics) header(cw *countingWriter, name, kind, help string) {
+	cw.printf("# HELP %shttp_client_%s %s\n# TYPE %shttp_client_%s %s\n", pm.prefix, name, help, pm.prefix, name, kind)
+}
+
+func (pm *PrometheusMetrics) sample(cw *countingWriter, name, labels string, value float64) {
+	cw.printf("%shttp_client_%s{%s} %s\n", pm.prefix, name, labels, formatFloat(value))
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func sortedLabels(n int, visit func(add func(CallLabels))) []CallLabels {
+	sorted := make([]CallLabels, 0, n)
+	visit(func(labels CallLabels) {
+		sorted = append(sorted, labels)
+	})
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.StatusClass < b.StatusClass
+	})
+	return sorted
+}
+
+func formatLabels(labels CallLabels, withStatus bool) string {
+	formatted := `service="` + escapeLabel(labels.Service) +
+		`",method="` + escapeLabel(labels.Method) +
+		`",path="` + escapeLabel(labels.Path) + `"`
+	if withStatus {
+		formatted += `,status_class="` + escapeLabel(labels.StatusClass) + `"`
+	}
+	return formatted
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,118 @@
+package refasthttp
+
+import (
+	"bytes"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFactory_Metrics(t *testing.T) {
+	release := make(chan struct{})
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		if ctx.QueryArgs().Has("hold") {
+			<-release
+		}
+		status, err := strconv.Atoi(string(ctx.QueryArgs().Peek("status")))
+		if err == nil {
+			ctx.SetStatusCode(status)
+		}
+		ctx.WriteString("pong")
+	})
+	defer fx.Finish()
+	bln := reFastHttpFixture.Balancer(map[string][]string{"users": {fx.Address()}})
+
+	t.Run("expect calls to be counted by path template and status class", func(t *testing.T) {
+		metrics := NewPrometheusMetrics("")
+		factory := NewFactory().Balancer(bln).Metrics(metrics)
+		for _, id := range []string{"1", "2"} {
+			_, err := factory.Service("users").POST("/users/{id}").(Builder).PathParam("id", id).
+				Encoder(reFastHttpFixture.Encoder()).
+				ToEncode("ping").
+				Go()
+			require.NoError(t, err)
+		}
+		_, err := factory.Service("users").POST("/users/{id}").(Builder).
+			PathParam("id", "3").
+			AddQueryParam("status", "503").
+			Go()
+		require.NoError(t, err)
+
+		labels := `service="users",method="POST",path="/users/{id}"`
+		out := scrape(t, metrics)
+		assert.Contains(t, out, "# TYPE http_client_requests_total counter\n")
+		assert.Contains(t, out, "http_client_requests_total{"+labels+`,status_class="2xx"} 2`+"\n")
+		assert.Contains(t, out, "http_client_requests_total{"+labels+`,status_class="5xx"} 1`+"\n")
+		assert.Contains(t, out, "# TYPE http_client_request_duration_seconds histogram\n")
+		assert.Contains(t, out, "http_client_request_duration_seconds_bucket{"+labels+`,status_class="2xx",le="+Inf"} 2`+"\n")
+		assert.Contains(t, out, "http_client_request_duration_seconds_count{"+labels+`,status_class="2xx"} 2`+"\n")
+		assert.Contains(t, out, "http_client_requests_in_flight{"+labels+"} 0\n")
+		assert.Contains(t, out, "http_client_request_bytes_total{"+labels+"} 12\n")
+		assert.Contains(t, out, "http_client_response_bytes_total{"+labels+"} 12\n")
+		assert.Contains(t, out, "http_client_errors_total{"+labels+"} 1\n")
+		assert.NotContains(t, out, "/users/1")
+	})
+
+	t.Run("expect paths without template to share one label", func(t *testing.T) {
+		metrics := NewPrometheusMetrics("")
+		factory := NewFactory().Metrics(metrics)
+		for _, id := range []string{"1", "2"} {
+			_, err := factory.To(fx.Address()).GET("/users/" + id).Go()
+			require.NoError(t, err)
+		}
+
+		out := scrape(t, metrics)
+		assert.Contains(t, out, `http_client_requests_total{service="",method="GET",path="other",status_class="2xx"} 2`)
+		assert.NotContains(t, out, "/users/")
+	})
+
+	t.Run("expect transport failures in error class", func(t *testing.T) {
+		metrics := NewPrometheusMetrics("shop")
+		_, err := NewFactory().Metrics(metrics).To(closedAddress(t)).GET("/").Go()
+		require.Error(t, err)
+
+		out := scrape(t, metrics)
+		assert.Contains(t, out, `shop_http_client_requests_total{service="",method="GET",path="other",status_class="error"} 1`)
+		assert.Contains(t, out, `shop_http_client_errors_total{service="",method="GET",path="other"} 1`)
+	})
+
+	t.Run("expect calls in flight to be gauged", func(t *testing.T) {
+		metrics := NewPrometheusMetrics("")
+		done := make(chan error)
+		go func() {
+			_, err := NewFactory().Metrics(metrics).To(fx.Address()).GET("/hold").QueryParam("hold", "1").Go()
+			done <- err
+		}()
+		gauge := `http_client_requests_in_flight{service="",method="GET",path="other"} `
+		eventually(t, func() bool {
+			return bytes.Contains([]byte(scrape(t, metrics)), []byte(gauge+"1\n"))
+		})
+		close(release)
+		require.NoError(t, <-done)
+		assert.Contains(t, scrape(t, metrics), gauge+"0\n")
+	})
+
+	t.Run("expect metrics to be served", func(t *testing.T) {
+		metrics := NewPrometheusMetrics("")
+		metrics.Finished(CallLabels{Service: `a"b`, Method: "GET", Path: "/", StatusClass: "2xx"}, CallResult{Latency: time.Millisecond})
+		server := reFastHttpFixture.New(t, metrics.Handler())
+		defer server.Finish()
+
+		res, err := NewFactory().To(server.Address()).GET("/metrics").Go()
+		require.NoError(t, err)
+		assert.Contains(t, string(res.Body()), `http_client_request_duration_seconds_bucket{service="a\"b",method="GET",path="/",status_class="2xx",le="0.005"} 1`)
+		assert.Contains(t, string(res.Body()), `http_client_request_duration_seconds_sum{service="a\"b",method="GET",path="/",status_class="2xx"} 0.001`)
+	})
+}
+
+func scrape(t *testing.T, metrics *PrometheusMetrics) string {
+	var buf bytes.Buffer
+	n, err := metrics.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), n)
+	return buf.String()
+}
@@ -0,0 +1,347 @@
+package refasthttp
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedProxy = errors.New("unsupported proxy scheme")
+	ErrProxyConnect     = errors.New("proxy refused to connect")
+)
+
+const defaultProxyHandshakeTimeout = 10 * time.Second
+
+// ProxyConfig selects an egress proxy by request scheme with NO_PROXY style
+// bypass rules. Proxy URLs use the http or socks5 scheme, credentials are
+// taken from the URL user info.
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	// HandshakeTimeout bounds the CONNECT or SOCKS5 handshake with the
+	// proxy, zero means 10 seconds.
+	HandshakeTimeout time.Duration
+}
+
+// ProxyFromEnvironment reads HTTP_PROXY, HTTPS_PROXY and NO_PROXY or their
+// lowercase variants.
+func ProxyFromEnvironment() ProxyConfig {
+	return ProxyConfig{
+		HTTPProxy:  getEnvAny("HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: getEnvAny("HTTPS_PROXY", "https_proxy"),
+		NoProxy:    getEnvAny("NO_PROXY", "no_proxy"),
+	}
+}
+
+func (f *Factory) Proxy(config ProxyConfig) *Factory {
+	f.proxy = &config
+	return f
+}
+
+// Proxy overrides the factory proxy for this request, an empty url connects directly.
+func (fhc *fastHttpClient) Proxy(proxyURL string) Builder {
+	fhc.proxy = &proxyURL
+	return fhc
+}
+
+func (config ProxyConfig) proxyFor(uri *fasthttp.URI) string {
+	if config.bypass(string(uri.Host())) {
+		return ""
+	}
+	if strings.EqualFold(string(uri.Scheme()), "https") {
+		return config.HTTPSProxy
+	}
+	return config.HTTPProxy
+}
+
+func (config ProxyConfig) bypass(hostport string) bool {
+	host, port := hostport, ""
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+
+	for _, rule := range strings.Split(config.NoProxy, ",") {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" {
+			continue
+		}
+		if rule == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(rule); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(rule); err == nil {
+			if p != port {
+				continue
+			}
+			rule = h
+		}
+		if ruleIP := net.ParseIP(rule); ruleIP != nil {
+			if ip != nil && ruleIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(rule, "*.") {
+			rule = rule[1:]
+		}
+		if strings.HasPrefix(rule, ".") {
+			if strings.HasSuffix(host, rule) {
+				return true
+			}
+			continue
+		}
+		if host == rule || strings.HasSuffix(host, "."+rule) {
+			return true
+		}
+	}
+	return false
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// proxies keeps one client per proxy URL, every client tunnels all of its
+// connections through that proxy from its Dial hook. Pinned https requests
+// get a client of their own which does the TLS handshake in the tunnel.
+type proxies struct {
+	mu      sync.Mutex
+	clients map[string]*fasthttp.Client
+}
+
+func (f *Factory) proxyClient(proxyURL string, pinned bool) (client *fasthttp.Client, err error) {
+	key := proxyURL
+	if pinned {
+		key = "pinned " + proxyURL
+	}
+	f.proxies.mu.Lock()
+	defer f.proxies.mu.Unlock()
+	if client, ok := f.proxies.clients[key]; ok {
+		return client, nil
+	}
+	raw := proxyURL
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return
+	}
+	timeout := defaultProxyHandshakeTimeout
+	if f.proxy != nil && f.proxy.HandshakeTimeout > 0 {
+		timeout = f.proxy.HandshakeTimeout
+	}
+	dial, err := proxyDialer(proxy, f.dialDirect, timeout)
+	if err != nil {
+		return
+	}
+	if f.proxies.clients == nil {
+		f.proxies.clients = make(map[string]*fasthttp.Client)
+	}
+	dial = f.pool.track(untimed(dial))
+	if pinned {
+		dial = f.pinning.dial(dial, f.tls)
+	}
+	client = f.cloneClient(dial)
+	f.proxies.clients[key] = client
+	return
+}
+
+func proxyDialer(proxy *url.URL, dial fasthttp.DialFunc, timeout time.Duration) (fasthttp.DialFunc, error) {
+	switch strings.ToLower(proxy.Scheme) {
+	case "http", "":
+		return func(addr string) (net.Conn, error) {
+			return dialHTTPProxy(proxy, dial, timeout, addr)
+		}, nil
+	case "socks5", "socks5h":
+		return func(addr string) (net.Conn, error) {
+			return dialSOCKS5(proxy, dial, timeout, addr)
+		}, nil
+	}
+	return nil, fmt.Errorf("%s: %w", proxy.Scheme, ErrUnsupportedProxy)
+}
+
+func proxyAddress(proxy *url.URL, defaultPort string) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	return net.JoinHostPort(proxy.Hostname(), defaultPort)
+}
+
+// handshake runs shake on conn within timeout and clears the deadline after
+// it, the connection is closed when the handshake fails.
+func handshake(conn net.Conn, timeout time.Duration, shake func() error) (err error) {
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err == nil {
+		if err = shake(); err == nil {
+			err = conn.SetDeadline(time.Time{})
+		}
+	}
+	if err != nil {
+		conn.Close()
+	}
+	return
+}
+
+func dialHTTPProxy(proxy *url.URL, dial fasthttp.DialFunc, timeout time.Duration, addr string) (conn net.Conn, err error) {
+	conn, err = dial(proxyAddress(proxy, "80"))
+	if err != nil {
+		return
+	}
+	if err = handshake(conn, timeout, func() error {
+		return connectHTTPProxy(conn, proxy.User, addr)
+	}); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func connectHTTPProxy(conn net.Conn, user *url.Userinfo, addr string) error {
+	request := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		request += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		return err
+	}
+	// the proxy stays silent after its response until the tunnel is used,
+	// so nothing is lost when the buffered reader is dropped
+	res, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %w", res.Status, ErrProxyConnect)
+	}
+	return nil
+}
+
+func dialSOCKS5(proxy *url.URL, dial fasthttp.DialFunc, timeout time.Duration, addr string) (conn net.Conn, err error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return
+	}
+	conn, err = dial(proxyAddress(proxy, "1080"))
+	if err != nil {
+		return
+	}
+	if err = handshake(conn, timeout, func() error {
+		return socks5Handshake(conn, proxy.User, host, port)
+	}); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func socks5Handshake(conn net.Conn, user *url.Userinfo, host string, port int) error {
+	methods := []byte{0x00}
+	if user != nil {
+		methods = []byte{0x00, 0x02}
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return fmt.Errorf("socks version %d: %w", reply[0], ErrProxyConnect)
+	}
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if user == nil {
+			return fmt.Errorf("socks authentication required: %w", ErrProxyConnect)
+		}
+		password, _ := user.Password()
+		auth := []byte{0x01, byte(len(user.Username()))}
+		auth = append(auth, user.Username()...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return fmt.Errorf("socks authentication failed: %w", ErrProxyConnect)
+		}
+	default:
+		return fmt.Errorf("no acceptable socks authentication method: %w", ErrProxyConnect)
+	}
+
+	request := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			request = append(append(request, 0x01), ip4...)
+		} else {
+			request = append(append(request, 0x04), ip.To16()...)
+		}
+	} else {
+		request = append(append(request, 0x03, byte(len(host))), host...)
+	}
+	request = append(request, 0, 0)
+	binary.BigEndian.PutUint16(request[len(request)-2:], uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("socks reply %d: %w", header[1], ErrProxyConnect)
+	}
+	var skip int
+	switch header[3] {
+	case 0x01:
+		skip = net.IPv4len
+	case 0x04:
+		skip = net.IPv6len
+	case 0x03:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		skip = int(length[0])
+	default:
+		return fmt.Errorf("socks address type %d: %w", header[3], ErrProxyConnect)
+	}
+	_, err := io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
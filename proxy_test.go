@@ -0,0 +1,197 @@
+package refasthttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixtureHost(fx *reFastHttpFixture.Fixture) string {
+	return fx.Address()[strings.Index(fx.Address(), "://")+3:]
+}
+
+func TestFactory_Proxy(t *testing.T) {
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("OK")
+	})
+	defer fx.Finish()
+
+	t.Run("expect request to be tunneled through http proxy", func(t *testing.T) {
+		proxy := reFastHttpFixture.NewHTTPProxy(t, "user", "secret")
+		defer proxy.Finish()
+
+		factory := NewFactory().Proxy(ProxyConfig{HTTPProxy: proxy.URL("user", "secret")})
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "OK", string(res.Body()))
+		assert.Equal(t, []string{fixtureHost(fx)}, proxy.Targets())
+	})
+
+	t.Run("expect error on wrong proxy credentials", func(t *testing.T) {
+		proxy := reFastHttpFixture.NewHTTPProxy(t, "user", "secret")
+		defer proxy.Finish()
+
+		factory := NewFactory().Proxy(ProxyConfig{HTTPProxy: proxy.URL("user", "wrong")})
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		assert.True(t, errors.Is(err, ErrProxyConnect))
+		assert.Nil(t, res)
+	})
+
+	t.Run("expect request to be tunneled through socks5 proxy", func(t *testing.T) {
+		proxy := reFastHttpFixture.NewSOCKS5Proxy(t, "user", "secret")
+		defer proxy.Finish()
+
+		factory := NewFactory().Proxy(ProxyConfig{HTTPProxy: proxy.URL("user", "secret")})
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "OK", string(res.Body()))
+		assert.Equal(t, []string{fixtureHost(fx)}, proxy.Targets())
+	})
+
+	t.Run("expect https to use https proxy", func(t *testing.T) {
+		certs := reFastHttpFixture.NewCertificates(t)
+		secure := reFastHttpFixture.NewTLS(t, certs, tls.NoClientCert, func(ctx *fasthttp.RequestCtx) {})
+		defer secure.Finish()
+		httpProxy := reFastHttpFixture.NewHTTPProxy(t, "", "")
+		defer httpProxy.Finish()
+		httpsProxy := reFastHttpFixture.NewHTTPProxy(t, "", "")
+		defer httpsProxy.Finish()
+
+		config, err := NewTLS().RootCA(certs.CAFile).Build()
+		require.NoError(t, err)
+		factory := NewFactory().
+			TLS(config).
+			Proxy(ProxyConfig{HTTPProxy: httpProxy.URL("", ""), HTTPSProxy: httpsProxy.URL("", "")})
+		_, err = factory.To(secure.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Empty(t, httpProxy.Targets())
+		assert.Equal(t, []string{fixtureHost(secure)}, httpsProxy.Targets())
+	})
+
+	t.Run("expect no proxy rules to bypass proxy", func(t *testing.T) {
+		proxy := reFastHttpFixture.NewHTTPProxy(t, "", "")
+		defer proxy.Finish()
+
+		factory := NewFactory().Proxy(ProxyConfig{HTTPProxy: proxy.URL("", ""), NoProxy: "127.0.0.0/8"})
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Empty(t, proxy.Targets())
+	})
+
+	t.Run("expect per request override", func(t *testing.T) {
+		proxy := reFastHttpFixture.NewHTTPProxy(t, "", "")
+		defer proxy.Finish()
+
+		factory := NewFactory().Proxy(ProxyConfig{HTTPProxy: proxy.URL("", "")})
+		_, err := factory.New().Proxy("").Address(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Empty(t, proxy.Targets())
+
+		_, err = NewFactory().New().Proxy(proxy.URL("", "")).Address(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Len(t, proxy.Targets(), 1)
+	})
+
+	t.Run("expect handshake with a silent proxy to time out", func(t *testing.T) {
+		silent, err := net.Listen("tcp4", "localhost:0")
+		require.NoError(t, err)
+		defer silent.Close()
+		go func() {
+			for {
+				conn, err := silent.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		for _, scheme := range []string{"http", "socks5"} {
+			factory := NewFactory().Proxy(ProxyConfig{
+				HTTPProxy:        scheme + "://" + silent.Addr().String(),
+				HandshakeTimeout: 50 * time.Millisecond,
+			})
+			started := time.Now()
+			_, err = factory.To(fx.Address()).GET("/").Go()
+			var netErr net.Error
+			require.True(t, errors.As(err, &netErr), scheme)
+			assert.True(t, netErr.Timeout(), scheme)
+			assert.True(t, time.Since(started) < time.Second, scheme)
+		}
+	})
+
+	t.Run("expect handshake deadline to be cleared for the tunnel", func(t *testing.T) {
+		slow := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			time.Sleep(100 * time.Millisecond)
+			ctx.WriteString("OK")
+		})
+		defer slow.Finish()
+		httpProxy := reFastHttpFixture.NewHTTPProxy(t, "", "")
+		defer httpProxy.Finish()
+		socksProxy := reFastHttpFixture.NewSOCKS5Proxy(t, "", "")
+		defer socksProxy.Finish()
+
+		for _, proxyURL := range []string{httpProxy.URL("", ""), socksProxy.URL("", "")} {
+			factory := NewFactory().Proxy(ProxyConfig{HTTPProxy: proxyURL, HandshakeTimeout: 50 * time.Millisecond})
+			res, err := factory.To(slow.Address()).GET("/").Go()
+			require.NoError(t, err, proxyURL)
+			assert.Equal(t, "OK", string(res.Body()))
+		}
+	})
+
+	t.Run("expect error on unsupported proxy scheme", func(t *testing.T) {
+		_, err := NewFactory().New().Proxy("ftp://localhost:21").Address(fx.Address()).GET("/").Go()
+		assert.True(t, errors.Is(err, ErrUnsupportedProxy))
+	})
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"} {
+		value, ok := os.LookupEnv(name)
+		os.Unsetenv(name)
+		if ok {
+			defer os.Setenv(name, value)
+		}
+	}
+	os.Setenv("http_proxy", "http://proxy:3128")
+	defer os.Unsetenv("http_proxy")
+	os.Setenv("HTTPS_PROXY", "socks5://proxy:1080")
+	defer os.Unsetenv("HTTPS_PROXY")
+	os.Setenv("NO_PROXY", "localhost,.internal")
+	defer os.Unsetenv("NO_PROXY")
+
+	assert.Equal(t, ProxyConfig{
+		HTTPProxy:  "http://proxy:3128",
+		HTTPSProxy: "socks5://proxy:1080",
+		NoProxy:    "localhost,.internal",
+	}, ProxyFromEnvironment())
+}
+
+func TestProxyConfig_bypass(t *testing.T) {
+	config := ProxyConfig{NoProxy: "example.com, .internal, 10.0.0.0/8, 192.168.1.1, api.partner.io:8443, *.corp"}
+	for host, expected := range map[string]bool{
+		"example.com":         true,
+		"www.example.com":     true,
+		"badexample.com":      false,
+		"service.internal":    true,
+		"internal":            false,
+		"10.1.2.3:80":         true,
+		"11.1.2.3":            false,
+		"192.168.1.1:443":     true,
+		"api.partner.io:8443": true,
+		"api.partner.io:443":  false,
+		"git.corp":            true,
+		"partner.com":         false,
+	} {
+		assert.Equal(t, expected, config.bypass(host), host)
+	}
+	assert.True(t, ProxyConfig{NoProxy: "*"}.bypass("anything:80"))
+}
@@ -0,0 +1,230 @@
+package refasthttp
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const queryTag = "query"
+
+var (
+	ErrQueryStructType = errors.New("query struct must be a struct or a pointer to a struct")
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (fhc *fastHttpClient) AddQueryParam(key, value string) Builder {
+	fhc.query.Add(key, value)
+	return fhc
+}
+
+func (fhc *fastHttpClient) QueryParams(values url.Values) Builder {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range values[key] {
+			fhc.query.Add(key, value)
+		}
+	}
+	return fhc
+}
+
+// mergeQuery moves the params of the builder into the URI, so sending it
+// again doesn't repeat them. Keys set with QueryParam replace the values of
+// the address, the others are added to them.
+func (fhc *fastHttpClient) mergeQuery() {
+	args := fhc.uri.QueryArgs()
+	for key := range fhc.querySet {
+		args.Del(key)
+	}
+	fhc.query.VisitAll(func(key, value []byte) {
+		args.AddBytesKV(key, value)
+	})
+	fhc.query.Reset()
+	fhc.querySet = nil
+}
+
+// QueryStruct adds every exported field of object as a query parameter.
+// Field names are taken from the `query:"name,omitempty"` tag, a tag of "-"
+// skips the field. Time values are formatted as RFC 3339 unless the tag
+// carries the "unix" option.
+func (fhc *fastHttpClient) QueryStruct(object interface{}) Builder {
+	values, err := encodeQuery(object)
+	if err != nil {
+		fhc.err = err
+		return fhc
+	}
+	return fhc.QueryParams(values)
+}
+
+func encodeQuery(object interface{}) (values url.Values, err error) {
+	value := reflect.ValueOf(object)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			err = ErrQueryStructType
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		err = ErrQueryStructType
+		return
+	}
+	values = url.Values{}
+	err = encodeQueryStruct(values, value)
+	return
+}
+
+func encodeQueryStruct(values url.Values, value reflect.Value) error {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get(queryTag)
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseQueryTag(tag)
+		fieldValue := value.Field(i)
+
+		if field.Anonymous && name == "" {
+			for fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					break
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+				if err := encodeQueryStruct(values, fieldValue); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if opts.has("omitempty") && isEmptyValue(fieldValue) {
+			continue
+		}
+		if err := encodeQueryValue(values, name, fieldValue, opts); err != nil {
+			return fmt.Errorf("query field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func encodeQueryValue(values url.Values, name string, value reflect.Value, opts queryTagOptions) error {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		if value.Type().Elem().Kind() == reflect.Uint8 && value.Kind() == reflect.Slice {
+			values.Add(name, string(value.Bytes()))
+			return nil
+		}
+		for i := 0; i < value.Len(); i++ {
+			if err := encodeQueryValue(values, name, value.Index(i), opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	str, err := formatQueryValue(value, opts)
+	if err != nil {
+		return err
+	}
+	values.Add(name, str)
+	return nil
+}
+
+func formatQueryValue(value reflect.Value, opts queryTagOptions) (string, error) {
+	if value.Type() == timeType {
+		t := value.Interface().(time.Time)
+		if opts.has("unix") {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+		return t.Format(time.RFC3339), nil
+	}
+	if value.Type().Implements(textMarshalerType) {
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(textMarshalerType) {
+		text, err := value.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
+	}
+	if stringer, ok := value.Interface().(fmt.Stringer); ok {
+		return stringer.String(), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", value.Type())
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).IsZero()
+	}
+	return false
+}
+
+type queryTagOptions []string
+
+func (opts queryTagOptions) has(option string) bool {
+	for _, opt := range opts {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func parseQueryTag(tag string) (name string, opts queryTagOptions) {
+	parts := strings.Split(tag, ",")
+	return parts[0], queryTagOptions(parts[1:])
+}
@@ -0,0 +1,153 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFastHttpClient_AddQueryParam(t *testing.T) {
+	t.Run("expect repeated keys to be sent", func(t *testing.T) {
+		queryKey := trifle.String()
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			values := ctx.QueryArgs().PeekMulti(queryKey)
+			assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, values)
+		})
+		defer fx.Finish()
+
+		res, err := New().
+			AddQueryParam(queryKey, "a").
+			AddQueryParam(queryKey, "b").
+			Address(fx.Address()).
+			GET("/").
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status())
+	})
+}
+
+func TestFastHttpClient_MergeQuery(t *testing.T) {
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Write(ctx.QueryArgs().QueryString())
+	})
+	defer fx.Finish()
+
+	t.Run("expect query param to replace values of the address", func(t *testing.T) {
+		res, err := New().
+			Address(fx.Address()+"/?a=1&b=1&a=3").
+			GET("/").
+			QueryParam("a", "2").
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, "b=1&a=2", string(res.Body()))
+	})
+
+	t.Run("expect query param to replace added values", func(t *testing.T) {
+		res, err := New().
+			AddQueryParam("a", "1").
+			AddQueryParam("a", "2").
+			Address(fx.Address()).
+			QueryParam("a", "3").(Builder).
+			AddQueryParam("a", "4").
+			GET("/").
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, "a=3&a=4", string(res.Body()))
+	})
+
+	t.Run("expect params not to repeat when sent again", func(t *testing.T) {
+		builder := New().
+			AddQueryParam("a", "1").
+			Address(fx.Address()).
+			QueryParam("b", "2").
+			GET("/")
+		for i := 0; i < 2; i++ {
+			res, err := builder.Go()
+			require.NoError(t, err)
+			assert.Equal(t, "a=1&b=2", string(res.Body()))
+		}
+	})
+}
+
+func TestFastHttpClient_QueryParams(t *testing.T) {
+	t.Run("expect all values to be sent", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			assert.Equal(t, "tag=a&tag=b&x=1", string(ctx.QueryArgs().QueryString()))
+		})
+		defer fx.Finish()
+
+		res, err := New().
+			QueryParams(url.Values{"x": {"1"}, "tag": {"a", "b"}}).
+			Address(fx.Address()).
+			GET("/").
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status())
+	})
+}
+
+type Paging struct {
+	Limit  int `query:"limit,omitempty"`
+	Offset int `query:"offset"`
+}
+
+type Search struct {
+	Paging
+	Tags     []string   `query:"tag"`
+	Since    time.Time  `query:"since"`
+	Until    *time.Time `query:"until,unix"`
+	Archived bool       `query:"archived"`
+	Owner    *string    `query:"owner,omitempty"`
+	Hidden   string     `query:"-"`
+	Note     string     `query:"note,omitempty"`
+}
+
+func TestEncodeQuery(t *testing.T) {
+	t.Run("expect struct fields to be encoded", func(t *testing.T) {
+		since := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+		until := since.Add(time.Hour)
+		values, err := encodeQuery(&Search{
+			Paging:   Paging{Offset: 10},
+			Tags:     []string{"a", "b"},
+			Since:    since,
+			Until:    &until,
+			Archived: true,
+			Hidden:   trifle.String(),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"offset":   {"10"},
+			"tag":      {"a", "b"},
+			"since":    {"2021-01-02T03:04:05Z"},
+			"until":    {"1609560245"},
+			"archived": {"true"},
+		}, values)
+	})
+
+	t.Run("expect pointer fields to be dereferenced", func(t *testing.T) {
+		owner := trifle.String()
+		values, err := encodeQuery(Search{Owner: &owner})
+		require.NoError(t, err)
+		assert.Equal(t, owner, values.Get("owner"))
+	})
+
+	t.Run("expect error on non struct", func(t *testing.T) {
+		_, err := encodeQuery(trifle.String())
+		assert.Equal(t, ErrQueryStructType, err)
+	})
+
+	t.Run("expect error to be returned from Go", func(t *testing.T) {
+		res, err := New().
+			QueryStruct(trifle.Int()).
+			Address(trifle.String()).
+			GET("/").
+			Go()
+		assert.Equal(t, ErrQueryStructType, err)
+		assert.Nil(t, res)
+	})
+}
@@ -0,0 +1,215 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// epochThreshold tells X-RateLimit-Reset epoch timestamps from delays.
+const epochThreshold = 1000000000
+
+var (
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+// RateLimit is a token bucket: Rate requests per second on average with
+// bursts of up to Burst. Without Wait requests beyond the limit fail with
+// ErrRateLimited, with Wait they are delayed until a token is available or
+// the context of the builder is done.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+	Wait  bool
+}
+
+// RateLimit limits the requests to a service name or a host, given as
+// "host" or "host:port". A service limit applies to every node found by
+// Service. Limits follow the X-RateLimit-* and RateLimit-* headers of the
+// responses: the rate drops to spread the remaining requests until the
+// reset, and no requests are sent once none remain. A Rate of zero or less
+// denies every request with ErrRateLimited, even with Wait.
+func (f *Factory) RateLimit(key string, limit RateLimit) *Factory {
+	f.limiters.mu.Lock()
+	defer f.limiters.mu.Unlock()
+	if f.limiters.buckets == nil {
+		f.limiters.buckets = make(map[string]*tokenBucket)
+	}
+	f.limiters.buckets[key] = newTokenBucket(limit)
+	return f
+}
+
+type limiters struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (l *limiters) find(fhc *fastHttpClient) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buckets) == 0 {
+		return nil
+	}
+	if bucket, ok := l.buckets[fhc.service]; ok && fhc.service != "" {
+		return bucket
+	}
+	host := string(fhc.uri.Host())
+	if bucket, ok := l.buckets[host]; ok {
+		return bucket
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return l.buckets[hostname]
+	}
+	return nil
+}
+
+type tokenBucket struct {
+	limit RateLimit
+	now   func() time.Time
+
+	mu            sync.Mutex
+	tokens        float64
+	last          time.Time
+	adjusted      float64
+	adjustedUntil time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &tokenBucket{
+		limit:  limit,
+		now:    time.Now,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+}
+
+// take removes a token and returns how long the caller has to wait for it,
+// ok is false when the caller can't wait and no token is available.
+func (tb *tokenBucket) take() (delay time.Duration, ok bool) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if tb.limit.Rate <= 0 {
+		return 0, false
+	}
+	now := tb.now()
+	tb.refill(now)
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return 0, true
+	}
+	if !tb.limit.Wait {
+		return 0, false
+	}
+	rate := tb.rate(now)
+	if rate <= 0 {
+		// nothing refills before the reset announced by the server
+		delay = tb.adjustedUntil.Sub(now)
+		rate = tb.limit.Rate
+	}
+	tb.tokens--
+	delay += time.Duration(-tb.tokens / rate * float64(time.Second))
+	return delay, true
+}
+
+// giveBack returns a token taken by a caller which stopped waiting.
+func (tb *tokenBucket) giveBack() {
+	tb.mu.Lock()
+	tb.tokens++
+	tb.mu.Unlock()
+}
+
+func (tb *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(tb.last)
+	tb.last = now
+	if elapsed <= 0 {
+		return
+	}
+	tb.tokens = math.Min(float64(tb.limit.Burst), tb.tokens+elapsed.Seconds()*tb.rate(now))
+}
+
+func (tb *tokenBucket) rate(now time.Time) float64 {
+	if now.Before(tb.adjustedUntil) {
+		return tb.adjusted
+	}
+	return tb.limit.Rate
+}
+
+// observe adjusts the bucket to the rate limit headers of resp.
+func (tb *tokenBucket) observe(resp *fasthttp.Response) {
+	remaining, ok := rateLimitHeader(resp, "RateLimit-Remaining", "X-RateLimit-Remaining")
+	if !ok {
+		return
+	}
+	reset, ok := rateLimitHeader(resp, "RateLimit-Reset", "X-RateLimit-Reset")
+	if !ok {
+		return
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	now := tb.now()
+	tb.refill(now)
+	var until time.Time
+	if reset >= epochThreshold {
+		until = time.Unix(int64(reset), 0)
+	} else {
+		until = now.Add(time.Duration(reset * float64(time.Second)))
+	}
+	if !until.After(now) {
+		return
+	}
+	tb.tokens = math.Min(tb.tokens, remaining)
+	tb.adjusted = math.Min(tb.limit.Rate, remaining/until.Sub(now).Seconds())
+	tb.adjustedUntil = until
+}
+
+func rateLimitHeader(resp *fasthttp.Response, names ...string) (value float64, ok bool) {
+	for _, name := range names {
+		raw := resp.Header.Peek(name)
+		if len(raw) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil || value < 0 {
+			continue
+		}
+		return value, true
+	}
+	return
+}
+
+// throttle waits for a token of the limiter of fhc, bucket is nil when the
+// request isn't limited.
+func (fhc *fastHttpClient) throttle() (bucket *tokenBucket, err error) {
+	bucket = fhc.factory.limiters.find(fhc)
+	if bucket == nil {
+		return
+	}
+	delay, ok := bucket.take()
+	if !ok {
+		return nil, ErrRateLimited
+	}
+	if delay <= 0 {
+		return
+	}
+	ctx := fhc.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return
+	case <-ctx.Done():
+		bucket.giveBack()
+		return nil, ctx.Err()
+	}
+}
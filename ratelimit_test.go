@@ -0,0 +1,122 @@
+package refasthttp
+
+import (
+	"context"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFactory_RateLimit(t *testing.T) {
+	var hits int32
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&hits, 1)
+		if remaining := ctx.QueryArgs().Peek("remaining"); len(remaining) > 0 {
+			ctx.Response.Header.SetBytesV("X-RateLimit-Remaining", remaining)
+			ctx.Response.Header.Set("X-RateLimit-Reset", "60")
+		}
+	})
+	defer fx.Finish()
+	hostname, _, err := net.SplitHostPort(fixtureHost(fx))
+	require.NoError(t, err)
+
+	t.Run("expect error beyond burst without wait", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().RateLimit(hostname, RateLimit{Rate: 0.1, Burst: 2})
+		for i := 0; i < 2; i++ {
+			_, err := factory.To(fx.Address()).GET("/").Go()
+			require.NoError(t, err)
+		}
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		assert.Equal(t, ErrRateLimited, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("expect wait for next token", func(t *testing.T) {
+		factory := NewFactory().RateLimit(fixtureHost(fx), RateLimit{Rate: 20, Wait: true})
+		started := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := factory.To(fx.Address()).GET("/").Go()
+			require.NoError(t, err)
+		}
+		assert.True(t, time.Since(started) >= 90*time.Millisecond)
+	})
+
+	t.Run("expect wait to stop with context", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().RateLimit(hostname, RateLimit{Rate: 0.1, Wait: true})
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = factory.To(fx.Address()).GET("/").(Builder).Context(ctx).Go()
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("expect service limit for every node", func(t *testing.T) {
+		bln := reFastHttpFixture.Balancer(map[string][]string{"service": {fx.Address()}})
+		factory := NewFactory().Balancer(bln).RateLimit("service", RateLimit{Rate: 0.1})
+		_, err := factory.Service("service").GET("/").Go()
+		require.NoError(t, err)
+		_, err = factory.Service("service").GET("/").Go()
+		assert.Equal(t, ErrRateLimited, err)
+		_, err = factory.To(fx.Address()).GET("/").Go()
+		assert.NoError(t, err)
+	})
+
+	t.Run("expect no requests once server reports none remaining", func(t *testing.T) {
+		factory := NewFactory().RateLimit(hostname, RateLimit{Rate: 100, Burst: 10})
+		_, err := factory.To(fx.Address()).GET("/").QueryParam("remaining", "0").Go()
+		require.NoError(t, err)
+		_, err = factory.To(fx.Address()).GET("/").Go()
+		assert.Equal(t, ErrRateLimited, err)
+	})
+
+	t.Run("expect limit without positive rate to deny every request", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().
+			RateLimit(hostname, RateLimit{Rate: 0, Burst: 5, Wait: true}).
+			RateLimit("service", RateLimit{Rate: -1, Burst: 5})
+		started := time.Now()
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		assert.Equal(t, ErrRateLimited, err)
+		assert.True(t, time.Since(started) < time.Second)
+
+		bln := reFastHttpFixture.Balancer(map[string][]string{"service": {fx.Address()}})
+		_, err = factory.Balancer(bln).Service("service").GET("/").Go()
+		assert.Equal(t, ErrRateLimited, err)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+	})
+}
+
+func TestTokenBucket_Observe(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(RateLimit{Rate: 10, Burst: 10, Wait: true})
+	bucket.now = func() time.Time { return now }
+	bucket.last = now
+
+	resp := &fasthttp.Response{}
+	resp.Header.Set("RateLimit-Remaining", "5")
+	resp.Header.Set("RateLimit-Reset", "10")
+	bucket.observe(resp)
+	assert.Equal(t, 0.5, bucket.rate(now))
+	assert.Equal(t, 5.0, bucket.tokens)
+
+	for i := 0; i < 5; i++ {
+		delay, ok := bucket.take()
+		require.True(t, ok)
+		assert.Equal(t, time.Duration(0), delay)
+	}
+	delay, ok := bucket.take()
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, delay)
+
+	assert.Equal(t, 10.0, bucket.rate(now.Add(10*time.Second)))
+}
@@ -0,0 +1,200 @@
+package refasthttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/valyala/fasthttp"
+	"strings"
+)
+
+const defaultRedactionMask = "REDACTED"
+
+// Redaction lists the values masked in everything the client logs and
+// traces, Before gets the URL and body as they are sent. Header and query parameter names are
+// matched case-insensitively. BodyFields are paths into JSON bodies such as
+// "user.password": "*" matches any single key or array element and "**" any
+// number of levels, so "**.password" masks password fields at any depth.
+// Form bodies are masked with the QueryParams list.
+type Redaction struct {
+	Headers     []string
+	QueryParams []string
+	BodyFields  []string
+	Mask        string
+}
+
+// DefaultRedaction masks credentials, cookies, API keys, tokens and
+// passwords. Factories use it unless told otherwise.
+func DefaultRedaction() Redaction {
+	return Redaction{
+		Headers: []string{
+			fasthttp.HeaderAuthorization,
+			fasthttp.HeaderProxyAuthorization,
+			fasthttp.HeaderCookie,
+			fasthttp.HeaderSetCookie,
+			"X-Api-Key",
+			"X-Auth-Token",
+		},
+		QueryParams: []string{
+			"api_key", "apikey", "key", "access_token", "token", "password", "secret",
+		},
+		BodyFields: []string{
+			"**.password", "**.secret", "**.token", "**.access_token", "**.refresh_token", "**.api_key",
+		},
+		Mask: defaultRedactionMask,
+	}
+}
+
+// Redaction replaces the redaction policy, an empty Redaction logs values
+// as they are.
+func (f *Factory) Redaction(redaction Redaction) *Factory {
+	if redaction.Mask == "" {
+		redaction.Mask = defaultRedactionMask
+	}
+	f.redaction = redaction
+	return f
+}
+
+func (r *Redaction) header(name []byte) bool {
+	return containsFold(r.Headers, name)
+}
+
+func (r *Redaction) query(name []byte) bool {
+	return containsFold(r.QueryParams, name)
+}
+
+func containsFold(names []string, name []byte) bool {
+	for _, candidate := range names {
+		if strings.EqualFold(candidate, string(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// url returns the full URI of uri with the denied query params masked.
+func (r *Redaction) url(uri *fasthttp.URI) string {
+	masked := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(masked)
+	uri.CopyTo(masked)
+	r.args(masked.QueryArgs())
+	return string(masked.FullURI())
+}
+
+// args masks every value of the denied keys, keys may repeat.
+func (r *Redaction) args(args *fasthttp.Args) {
+	masked := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(masked)
+	args.VisitAll(func(key, value []byte) {
+		if r.query(key) {
+			value = []byte(r.Mask)
+		}
+		masked.AddBytesKV(key, value)
+	})
+	masked.CopyTo(args)
+}
+
+// headers formats the headers visited by visit one per line.
+func (r *Redaction) headers(visit func(f func(key, value []byte))) string {
+	var b strings.Builder
+	visit(func(key, value []byte) {
+		b.Write(key)
+		b.WriteString(": ")
+		if r.header(key) {
+			b.WriteString(r.Mask)
+		} else {
+			b.Write(value)
+		}
+		b.WriteString("\n")
+	})
+	return b.String()
+}
+
+// body masks the denied fields of JSON and form bodies, other bodies and
+// bodies without denied fields are returned as they are.
+func (r *Redaction) body(contentType, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	if bytes.HasPrefix(contentType, []byte("application/x-www-form-urlencoded")) {
+		args := fasthttp.AcquireArgs()
+		defer fasthttp.ReleaseArgs(args)
+		args.ParseBytes(body)
+		denied := false
+		args.VisitAll(func(key, value []byte) {
+			denied = denied || r.query(key)
+		})
+		if !denied {
+			return body
+		}
+		r.args(args)
+		return args.QueryString()
+	}
+	if len(r.BodyFields) == 0 || !json.Valid(body) {
+		return body
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return body
+	}
+	masked := false
+	for _, field := range r.BodyFields {
+		document = r.mask(document, strings.Split(strings.TrimPrefix(field, "$."), "."), &masked)
+	}
+	if !masked {
+		return body
+	}
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+// mask replaces the values under path in node and sets masked when it
+// replaced any.
+func (r *Redaction) mask(node interface{}, path []string, masked *bool) interface{} {
+	if len(path) == 0 {
+		*masked = true
+		return r.Mask
+	}
+	segment, rest := path[0], path[1:]
+	if segment == "**" {
+		node = r.mask(node, rest, masked)
+		return r.each(node, func(child interface{}) interface{} {
+			return r.mask(child, path, masked)
+		})
+	}
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if segment == "*" || strings.EqualFold(segment, key) {
+				value[key] = r.mask(child, rest, masked)
+			}
+		}
+	case []interface{}:
+		if segment == "*" {
+			for i, child := range value {
+				value[i] = r.mask(child, rest, masked)
+			}
+		}
+	}
+	return node
+}
+
+func (r *Redaction) each(node interface{}, fn func(child interface{}) interface{}) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = fn(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = fn(child)
+		}
+	}
+	return node
+}
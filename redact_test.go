@@ -0,0 +1,131 @@
+package refasthttp
+
+import (
+	"fmt"
+	"github.com/remicro/api/net/rehttp"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+func TestFactory_Redaction(t *testing.T) {
+	secrets := make([]string, 8)
+	for i := range secrets {
+		secrets[i] = "secret" + trifle.String()
+	}
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderSetCookie, "session="+secrets[6])
+		ctx.Response.Header.SetContentType("application/json")
+		fmt.Fprintf(ctx, `{"label":"visible","secret":%q}`, secrets[7])
+	})
+	defer fx.Finish()
+
+	t.Run("expect redacted values never to reach the logger", func(t *testing.T) {
+		logger := &recordLogger{}
+		factory := NewFactory().Logger(logger).AccessLog(AccessLogConfig{Headers: true, Bodies: true})
+		_, err := factory.To(fx.Address()).
+			POST("/").
+			Header("Authorization", "Bearer "+secrets[0]).
+			Header("X-Api-Key", secrets[1]).
+			Cookie("session", []byte(secrets[2])).
+			QueryParam("api_key", secrets[3]).
+			QueryParam("page", "2").(Builder).
+			AddQueryParam("token", secrets[0]).
+			AddQueryParam("token", secrets[1]).
+			ContentType(rehttp.ContentTypeJson).
+			Encoder(reFastHttpFixture.Encoder()).
+			ToEncode(map[string]interface{}{
+				"user":  map[string]interface{}{"name": "visible", "password": secrets[4]},
+				"items": []interface{}{map[string]interface{}{"token": secrets[5]}},
+			}).
+			Go()
+		require.NoError(t, err)
+
+		entries := logger.Entries()
+		require.Len(t, entries, 1)
+		dump := fmt.Sprint(entries)
+		for _, secret := range secrets {
+			assert.NotContains(t, dump, secret)
+		}
+		assert.Contains(t, dump, "REDACTED")
+		assert.Contains(t, dump, "visible")
+		assert.Equal(t, "api_key=REDACTED&page=2&token=REDACTED&token=REDACTED", entries[0].Fields["query"])
+	})
+
+	t.Run("expect before to get the url and body as sent", func(t *testing.T) {
+		var url string
+		var body []byte
+		res, err := NewFactory().To(fx.Address()).
+			POST("/").
+			QueryParam("api_key", secrets[3]).
+			ContentType(rehttp.ContentTypeJson).
+			Encoder(reFastHttpFixture.Encoder()).
+			ToEncode(map[string]interface{}{"password": secrets[4]}).
+			Before(func(_ rehttp.Builder, sentURL string, sentBody []byte) {
+				url, body = sentURL, append([]byte(nil), sentBody...)
+			}).
+			Go()
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, fx.Address()+"/?api_key="+secrets[3], url)
+		assert.Contains(t, string(body), secrets[4])
+	})
+
+	t.Run("expect empty policy to log values as they are", func(t *testing.T) {
+		logger := &recordLogger{}
+		factory := NewFactory().Logger(logger).Redaction(Redaction{}).AccessLog(AccessLogConfig{Bodies: true})
+		_, err := factory.To(fx.Address()).GET("/").QueryParam("api_key", secrets[3]).Go()
+		require.NoError(t, err)
+
+		entry := logger.Entries()[0]
+		assert.Equal(t, "api_key="+secrets[3], entry.Fields["query"])
+		assert.Contains(t, entry.Fields["response_body"], secrets[7])
+	})
+}
+
+func TestRedaction_Body(t *testing.T) {
+	redaction := Redaction{
+		QueryParams: []string{"password"},
+		BodyFields:  []string{"user.password", "items.*.token", "**.secret"},
+		Mask:        "x",
+	}
+	json := []byte("application/json")
+
+	t.Run("expect masked json not to be html escaped", func(t *testing.T) {
+		body := redaction.body(json, []byte(`{"secret":"s","a":"<b>&"}`))
+		assert.Equal(t, `{"a":"<b>&","secret":"x"}`, string(body))
+	})
+
+	t.Run("expect json paths to be masked", func(t *testing.T) {
+		body := redaction.body(json, []byte(`{
+			"user": {"password": "p", "name": "n", "nested": {"password": "kept"}},
+			"items": [{"token": "t", "id": 1}],
+			"a": {"b": [{"secret": "s"}]},
+			"secret": "s"
+		}`))
+		assert.JSONEq(t, `{
+			"user": {"password": "x", "name": "n", "nested": {"password": "kept"}},
+			"items": [{"token": "x", "id": 1}],
+			"a": {"b": [{"secret": "x"}]},
+			"secret": "x"
+		}`, string(body))
+	})
+
+	t.Run("expect form bodies to be masked by query params", func(t *testing.T) {
+		body := redaction.body([]byte("application/x-www-form-urlencoded"), []byte("user=u&Password=p&password=q"))
+		assert.Equal(t, "user=u&Password=x&password=x", string(body))
+	})
+
+	t.Run("expect other bodies to be kept", func(t *testing.T) {
+		body := []byte(`{"password": "p"`)
+		assert.Equal(t, body, redaction.body(json, body))
+		body = []byte(`{"z":1, "a":"<b>&"}`)
+		assert.Equal(t, body, redaction.body(json, body))
+		body = []byte("user=u&a=%3Cb%3E")
+		assert.Equal(t, body, redaction.body([]byte("application/x-www-form-urlencoded"), body))
+		assert.Equal(t, "password=p", string(redaction.body([]byte("text/plain"), []byte("password=p"))))
+	})
+}
@@ -0,0 +1,126 @@
+package refasthttp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/valyala/fasthttp"
+)
+
+const defaultMaxRedirects = 10
+
+var (
+	ErrTooManyRedirects    = errors.New("too many redirects")
+	ErrRedirectHostChanged = errors.New("redirect to a different host is not allowed")
+	ErrMissingLocation     = errors.New("missing Location header for redirect")
+)
+
+// RedirectPolicy controls how redirect responses are followed. A zero
+// MaxHops means defaultMaxRedirects.
+type RedirectPolicy struct {
+	MaxHops      int
+	SameHostOnly bool
+}
+
+// RedirectHop describes a single redirect response in the chain.
+type RedirectHop struct {
+	URL      string
+	Status   int
+	Location string
+}
+
+func (fhc *fastHttpClient) Redirects(policy RedirectPolicy) Builder {
+	if policy.MaxHops <= 0 {
+		policy.MaxHops = defaultMaxRedirects
+	}
+	fhc.redirect = &policy
+	return fhc
+}
+
+func (res *responseImpl) Redirects() (hops []RedirectHop) {
+	return res.redirects
+}
+
+func (fhc *fastHttpClient) followRedirects(res *responseImpl) (err error) {
+	resp := res.response
+	explicitCookies := &fasthttp.Args{}
+	fhc.req.Header.VisitAllCookie(func(key, value []byte) {
+		explicitCookies.AddBytesKV(key, value)
+	})
+
+	for isRedirect(resp.StatusCode()) {
+		location := resp.Header.Peek(fasthttp.HeaderLocation)
+		if len(location) == 0 {
+			return ErrMissingLocation
+		}
+		res.redirects = append(res.redirects, RedirectHop{
+			URL:      string(fhc.uri.FullURI()),
+			Status:   resp.StatusCode(),
+			Location: string(location),
+		})
+		if len(res.redirects) > fhc.redirect.MaxHops {
+			return ErrTooManyRedirects
+		}
+
+		next := fasthttp.AcquireURI()
+		fhc.uri.CopyTo(next)
+		next.UpdateBytes(location)
+		sameHost := bytes.EqualFold(next.Host(), fhc.uri.Host())
+		if !sameHost && fhc.redirect.SameHostOnly {
+			fasthttp.ReleaseURI(next)
+			return ErrRedirectHostChanged
+		}
+
+		fhc.req.Header.DelAllCookies()
+		if sameHost {
+			explicitCookies.VisitAll(fhc.req.Header.SetCookieBytesKV)
+		} else {
+			fhc.req.Header.Del(fasthttp.HeaderAuthorization)
+			fhc.socket = ""
+		}
+		rewriteRedirectMethod(fhc.req, resp.StatusCode())
+
+		fasthttp.ReleaseURI(fhc.uri)
+		fhc.uri = next
+		fhc.req.Header.SetHostBytes(next.Host())
+		resp.Reset()
+		err = fhc.do(resp)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case fasthttp.StatusMovedPermanently,
+		fasthttp.StatusFound,
+		fasthttp.StatusSeeOther,
+		fasthttp.StatusTemporaryRedirect,
+		fasthttp.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// rewriteRedirectMethod follows RFC 7231: 303 switches to GET (HEAD stays
+// HEAD), 301 and 302 switch a POST to GET as user agents do, 307 and 308
+// keep both the method and the body.
+func rewriteRedirectMethod(req *fasthttp.Request, status int) {
+	switch status {
+	case fasthttp.StatusSeeOther:
+		if req.Header.IsHead() {
+			return
+		}
+	case fasthttp.StatusMovedPermanently, fasthttp.StatusFound:
+		if !req.Header.IsPost() {
+			return
+		}
+	default:
+		return
+	}
+	req.Header.SetMethod(fasthttp.MethodGet)
+	req.ResetBody()
+	req.Header.Del(fasthttp.HeaderContentType)
+	req.Header.SetContentLength(0)
+}
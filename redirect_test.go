@@ -0,0 +1,158 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+func TestFastHttpClient_Redirects(t *testing.T) {
+	t.Run("expect redirect not to be followed by default", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.Redirect("/next", fasthttp.StatusFound)
+		})
+		defer fx.Finish()
+
+		res, err := New().Address(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusFound, res.Status())
+	})
+
+	t.Run("expect chain to be followed and exposed", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			switch string(ctx.Path()) {
+			case "/a":
+				ctx.Redirect("/b", fasthttp.StatusMovedPermanently)
+			case "/b":
+				ctx.Redirect("/c", fasthttp.StatusTemporaryRedirect)
+			default:
+				ctx.Write([]byte("done"))
+			}
+		})
+		defer fx.Finish()
+
+		res, err := New().
+			Redirects(RedirectPolicy{}).
+			Address(fx.Address()).
+			GET("/a").
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, "done", string(res.Body()))
+		hops := res.(Response).Redirects()
+		require.Len(t, hops, 2)
+		assert.Equal(t, fx.Address()+"/a", hops[0].URL)
+		assert.Equal(t, fasthttp.StatusMovedPermanently, hops[0].Status)
+		assert.Equal(t, fx.Address()+"/b", hops[1].URL)
+		assert.Equal(t, fasthttp.StatusTemporaryRedirect, hops[1].Status)
+	})
+
+	t.Run("expect error on too many hops", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.Redirect("/", fasthttp.StatusFound)
+		})
+		defer fx.Finish()
+
+		res, err := New().
+			Redirects(RedirectPolicy{MaxHops: 3}).
+			Address(fx.Address()).
+			GET("/").
+			Go()
+		assert.Equal(t, ErrTooManyRedirects, err)
+		require.NotNil(t, res)
+		assert.Len(t, res.(Response).Redirects(), 4)
+	})
+
+	t.Run("expect 303 to switch to GET without body", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			if string(ctx.Path()) == "/form" {
+				ctx.Redirect("/result", fasthttp.StatusSeeOther)
+				return
+			}
+			assert.Equal(t, "GET", string(ctx.Method()))
+			assert.Empty(t, ctx.PostBody())
+		})
+		defer fx.Finish()
+
+		req := Object{Label: trifle.String()}
+		res, err := New().
+			Redirects(RedirectPolicy{}).
+			Address(fx.Address()).
+			POST("/form").
+			Encoder(reFastHttpFixture.Encoder()).
+			ToEncode(&req).
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status())
+	})
+
+	t.Run("expect 307 to preserve method and body", func(t *testing.T) {
+		req := Object{Label: trifle.String()}
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			if string(ctx.Path()) == "/old" {
+				ctx.Redirect("/new", fasthttp.StatusTemporaryRedirect)
+				return
+			}
+			assert.Equal(t, "POST", string(ctx.Method()))
+			var rr Object
+			assert.NoError(t, reFastHttpFixture.Decoder().Decode(&rr, ctx.PostBody()))
+			assert.Equal(t, req, rr)
+		})
+		defer fx.Finish()
+
+		res, err := New().
+			Redirects(RedirectPolicy{}).
+			Address(fx.Address()).
+			POST("/old").
+			Encoder(reFastHttpFixture.Encoder()).
+			ToEncode(&req).
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status())
+	})
+
+	t.Run("expect authorization to be stripped on cross host hop", func(t *testing.T) {
+		token := trifle.String()
+		target := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			assert.Empty(t, ctx.Request.Header.Peek("Authorization"))
+		})
+		defer target.Finish()
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			assert.Equal(t, token, string(ctx.Request.Header.Peek("Authorization")))
+			ctx.Redirect(target.Address()+"/", fasthttp.StatusFound)
+		})
+		defer fx.Finish()
+
+		res, err := New().
+			Redirects(RedirectPolicy{}).
+			Address(fx.Address()).
+			GET("/").
+			Header("Authorization", token).
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status())
+		assert.Len(t, res.(Response).Redirects(), 1)
+	})
+
+	t.Run("expect cross host hop to be refused for same host policy", func(t *testing.T) {
+		target := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			t.Error("unexpected request to another host")
+		})
+		defer target.Finish()
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.Redirect(target.Address()+"/", fasthttp.StatusFound)
+		})
+		defer fx.Finish()
+
+		res, err := New().
+			Redirects(RedirectPolicy{SameHostOnly: true}).
+			Address(fx.Address()).
+			GET("/").
+			Go()
+		assert.Equal(t, ErrRedirectHostChanged, err)
+		require.NotNil(t, res)
+		assert.Equal(t, fasthttp.StatusFound, res.Status())
+	})
+}
@@ -0,0 +1,336 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDNSTTL         = time.Minute
+	defaultNegativeDNSTTL = 5 * time.Second
+	defaultLookupTimeout  = 5 * time.Second
+	defaultDialTimeout    = 10 * time.Second
+	defaultFallbackDelay  = 300 * time.Millisecond
+)
+
+var (
+	ErrNoAddresses = errors.New("no addresses found for host")
+)
+
+// Resolver looks up the addresses of a host, *net.Resolver satisfies it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) (addrs []net.IPAddr, err error)
+}
+
+// CachingResolver caches lookups of another resolver. Answers live for the
+// TTL and are refreshed in the background once they get close to expiring,
+// hosts that do not exist are cached for the negative TTL. Other failures,
+// such as timeouts and canceled lookups, are not cached. Every lookup
+// rotates the cached addresses so connections are spread round-robin over
+// all records.
+type CachingResolver struct {
+	resolver     Resolver
+	ttl          time.Duration
+	negativeTTL  time.Duration
+	refreshAhead time.Duration
+	timeout      time.Duration
+	now          func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+type dnsEntry struct {
+	addrs      []net.IPAddr
+	err        error
+	resolved   time.Time
+	next       int
+	refreshing bool
+}
+
+// NewCachingResolver wraps resolver, nil means net.DefaultResolver.
+func NewCachingResolver(resolver Resolver) *CachingResolver {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &CachingResolver{
+		resolver:     resolver,
+		ttl:          defaultDNSTTL,
+		negativeTTL:  defaultNegativeDNSTTL,
+		refreshAhead: defaultDNSTTL / 5,
+		timeout:      defaultLookupTimeout,
+		now:          time.Now,
+		entries:      make(map[string]*dnsEntry),
+	}
+}
+
+// TTL sets how long answers are cached, background refresh starts during
+// the last fifth of it.
+func (cr *CachingResolver) TTL(ttl time.Duration) *CachingResolver {
+	cr.ttl = ttl
+	cr.refreshAhead = ttl / 5
+	return cr
+}
+
+func (cr *CachingResolver) NegativeTTL(ttl time.Duration) *CachingResolver {
+	cr.negativeTTL = ttl
+	return cr
+}
+
+func (cr *CachingResolver) LookupIPAddr(ctx context.Context, host string) (addrs []net.IPAddr, err error) {
+	cr.mu.Lock()
+	entry, ok := cr.entries[host]
+	if ok {
+		age := cr.now().Sub(entry.resolved)
+		if entry.err != nil && age < cr.negativeTTL {
+			cr.mu.Unlock()
+			return nil, entry.err
+		}
+		if entry.err == nil && age < cr.ttl {
+			addrs = entry.rotate()
+			if age >= cr.ttl-cr.refreshAhead && !entry.refreshing {
+				entry.refreshing = true
+				go cr.refresh(host)
+			}
+			cr.mu.Unlock()
+			return
+		}
+	}
+	cr.mu.Unlock()
+
+	addrs, err = cr.lookup(ctx, host)
+	if err != nil && !notFound(err) {
+		return nil, err
+	}
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	entry = cr.store(host, addrs, err)
+	if err != nil {
+		return
+	}
+	return entry.rotate(), nil
+}
+
+// notFound tells whether err is an answer that host does not exist.
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// Forget drops the cached answer for host.
+func (cr *CachingResolver) Forget(host string) {
+	cr.mu.Lock()
+	delete(cr.entries, host)
+	cr.mu.Unlock()
+}
+
+func (cr *CachingResolver) refresh(host string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cr.timeout)
+	defer cancel()
+	addrs, err := cr.lookup(ctx, host)
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if err != nil {
+		// keep serving the current answer until it expires
+		if entry, ok := cr.entries[host]; ok {
+			entry.refreshing = false
+		}
+		return
+	}
+	cr.store(host, addrs, nil)
+}
+
+func (cr *CachingResolver) lookup(ctx context.Context, host string) (addrs []net.IPAddr, err error) {
+	addrs, err = cr.resolver.LookupIPAddr(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = &net.DNSError{Err: ErrNoAddresses.Error(), Name: host, IsNotFound: true}
+	}
+	return
+}
+
+func (cr *CachingResolver) store(host string, addrs []net.IPAddr, err error) *dnsEntry {
+	entry := &dnsEntry{
+		addrs:    addrs,
+		err:      err,
+		resolved: cr.now(),
+	}
+	if old, ok := cr.entries[host]; ok {
+		entry.next = old.next
+	}
+	cr.entries[host] = entry
+	return entry
+}
+
+func (entry *dnsEntry) rotate() []net.IPAddr {
+	n := len(entry.addrs)
+	addrs := make([]net.IPAddr, 0, n)
+	start := entry.next % n
+	addrs = append(addrs, entry.addrs[start:]...)
+	addrs = append(addrs, entry.addrs[:start]...)
+	entry.next++
+	return addrs
+}
+
+// DualStackDialer resolves hosts with a Resolver and races IPv4 and IPv6
+// connections happy eyeballs style: the family of the first address is tried
+// first, the other one joins after the fallback delay or as soon as the
+// first family fails.
+type DualStackDialer struct {
+	resolver      Resolver
+	timeout       time.Duration
+	fallbackDelay time.Duration
+	dial          func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+func NewDualStackDialer(resolver Resolver) *DualStackDialer {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	dialer := &net.Dialer{}
+	return &DualStackDialer{
+		resolver:      resolver,
+		timeout:       defaultDialTimeout,
+		fallbackDelay: defaultFallbackDelay,
+		dial:          dialer.DialContext,
+	}
+}
+
+func (dsd *DualStackDialer) Timeout(timeout time.Duration) *DualStackDialer {
+	dsd.timeout = timeout
+	return dsd
+}
+
+func (dsd *DualStackDialer) FallbackDelay(delay time.Duration) *DualStackDialer {
+	dsd.fallbackDelay = delay
+	return dsd
+}
+
+func (dsd *DualStackDialer) Dial(addr string) (conn net.Conn, err error) {
+	conn, _, err = dsd.dialTimed(addr)
+	return
+}
+
+// dialTimed dials addr and reports how long resolving its host took.
+func (dsd *DualStackDialer) dialTimed(addr string) (conn net.Conn, resolving time.Duration, err error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dsd.timeout)
+	defer cancel()
+
+	if ip := net.ParseIP(host); ip != nil {
+		conn, err = dsd.dial(ctx, "tcp", addr)
+		return
+	}
+	started := time.Now()
+	addrs, err := dsd.resolver.LookupIPAddr(ctx, host)
+	resolving = time.Since(started)
+	if err != nil {
+		return
+	}
+	if len(addrs) == 0 {
+		err = &net.DNSError{Err: ErrNoAddresses.Error(), Name: host, IsNotFound: true}
+		return
+	}
+	primary, fallback := splitFamilies(addrs)
+	conn, err = dsd.race(ctx, port, primary, fallback)
+	return
+}
+
+type dialResult struct {
+	conn    net.Conn
+	err     error
+	primary bool
+}
+
+func (dsd *DualStackDialer) race(ctx context.Context, port string, primary, fallback []net.IPAddr) (net.Conn, error) {
+	if len(fallback) == 0 {
+		return dsd.dialSerial(ctx, port, primary)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult)
+	start := func(addrs []net.IPAddr, isPrimary bool) {
+		conn, err := dsd.dialSerial(ctx, port, addrs)
+		select {
+		case results <- dialResult{conn: conn, err: err, primary: isPrimary}:
+		case <-ctx.Done():
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}
+	go start(primary, true)
+
+	fallbackTimer := time.NewTimer(dsd.fallbackDelay)
+	defer fallbackTimer.Stop()
+
+	var firstErr error
+	primaryDone, fallbackStarted, fallbackDone := false, false, false
+	for {
+		select {
+		case <-fallbackTimer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				go start(fallback, false)
+			}
+		case result := <-results:
+			if result.err == nil {
+				return result.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			if result.primary {
+				primaryDone = true
+			} else {
+				fallbackDone = true
+			}
+			if primaryDone && !fallbackStarted {
+				fallbackTimer.Stop()
+				fallbackStarted = true
+				go start(fallback, false)
+			}
+			if primaryDone && fallbackDone {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+func (dsd *DualStackDialer) dialSerial(ctx context.Context, port string, addrs []net.IPAddr) (conn net.Conn, err error) {
+	for _, addr := range addrs {
+		conn, err = dsd.dial(ctx, "tcp", net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	return
+}
+
+func splitFamilies(addrs []net.IPAddr) (primary, fallback []net.IPAddr) {
+	primaryIsV4 := addrs[0].IP.To4() != nil
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == primaryIsV4 {
+			primary = append(primary, addr)
+		} else {
+			fallback = append(fallback, addr)
+		}
+	}
+	return
+}
+
+// Resolver makes the shared client resolve hosts with resolver and dial
+// them dual stack. A Dialer set on the factory takes precedence.
+func (f *Factory) Resolver(resolver Resolver) *Factory {
+	f.resolver = NewDualStackDialer(resolver)
+	return f
+}
@@ -0,0 +1,234 @@
+package refasthttp
+
+import (
+	"context"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeResolver struct {
+	mu    sync.Mutex
+	addrs map[string][]net.IPAddr
+	err   error
+	calls int
+}
+
+func (fr *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.calls++
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	return fr.addrs[host], nil
+}
+
+func (fr *fakeResolver) Calls() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.calls
+}
+
+func ipAddrs(ips ...string) (addrs []net.IPAddr) {
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return
+}
+
+func TestCachingResolver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("expect answers to be cached for ttl", func(t *testing.T) {
+		upstream := &fakeResolver{addrs: map[string][]net.IPAddr{"a.test": ipAddrs("10.0.0.1")}}
+		now := time.Now()
+		resolver := NewCachingResolver(upstream).TTL(time.Minute)
+		resolver.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			addrs, err := resolver.LookupIPAddr(ctx, "a.test")
+			require.NoError(t, err)
+			assert.Equal(t, ipAddrs("10.0.0.1"), addrs)
+		}
+		assert.Equal(t, 1, upstream.Calls())
+
+		now = now.Add(time.Minute)
+		_, err := resolver.LookupIPAddr(ctx, "a.test")
+		require.NoError(t, err)
+		assert.Equal(t, 2, upstream.Calls())
+	})
+
+	t.Run("expect unknown hosts to be cached for negative ttl", func(t *testing.T) {
+		upstream := &fakeResolver{err: &net.DNSError{Err: "no such host", Name: "a.test", IsNotFound: true}}
+		now := time.Now()
+		resolver := NewCachingResolver(upstream).NegativeTTL(time.Second)
+		resolver.now = func() time.Time { return now }
+
+		_, err := resolver.LookupIPAddr(ctx, "a.test")
+		assert.Equal(t, upstream.err, err)
+		_, err = resolver.LookupIPAddr(ctx, "a.test")
+		assert.Equal(t, upstream.err, err)
+		assert.Equal(t, 1, upstream.Calls())
+
+		now = now.Add(time.Second)
+		_, err = resolver.LookupIPAddr(ctx, "a.test")
+		assert.Error(t, err)
+		assert.Equal(t, 2, upstream.Calls())
+	})
+
+	t.Run("expect other failures not to be cached", func(t *testing.T) {
+		upstream := &fakeResolver{err: context.DeadlineExceeded}
+		resolver := NewCachingResolver(upstream)
+		_, err := resolver.LookupIPAddr(ctx, "a.test")
+		assert.Equal(t, context.DeadlineExceeded, err)
+		upstream.err = trifle.UnexpectedError()
+		_, err = resolver.LookupIPAddr(ctx, "a.test")
+		assert.Equal(t, upstream.err, err)
+
+		upstream.err = nil
+		upstream.addrs = map[string][]net.IPAddr{"a.test": ipAddrs("10.0.0.1")}
+		addrs, err := resolver.LookupIPAddr(ctx, "a.test")
+		require.NoError(t, err)
+		assert.Equal(t, ipAddrs("10.0.0.1"), addrs)
+		assert.Equal(t, 3, upstream.Calls())
+	})
+
+	t.Run("expect empty answer to be an error", func(t *testing.T) {
+		resolver := NewCachingResolver(&fakeResolver{})
+		_, err := resolver.LookupIPAddr(ctx, "a.test")
+		assert.Error(t, err)
+	})
+
+	t.Run("expect round robin over records", func(t *testing.T) {
+		upstream := &fakeResolver{addrs: map[string][]net.IPAddr{"a.test": ipAddrs("10.0.0.1", "10.0.0.2", "::1")}}
+		resolver := NewCachingResolver(upstream)
+
+		var firsts []string
+		for i := 0; i < 4; i++ {
+			addrs, err := resolver.LookupIPAddr(ctx, "a.test")
+			require.NoError(t, err)
+			require.Len(t, addrs, 3)
+			firsts = append(firsts, addrs[0].String())
+		}
+		assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "::1", "10.0.0.1"}, firsts)
+	})
+
+	t.Run("expect background refresh before expiry", func(t *testing.T) {
+		upstream := &fakeResolver{addrs: map[string][]net.IPAddr{"a.test": ipAddrs("10.0.0.1")}}
+		var mu sync.Mutex
+		now := time.Now()
+		resolver := NewCachingResolver(upstream).TTL(10 * time.Second)
+		resolver.now = func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}
+
+		_, err := resolver.LookupIPAddr(ctx, "a.test")
+		require.NoError(t, err)
+
+		upstream.mu.Lock()
+		upstream.addrs["a.test"] = ipAddrs("10.0.0.2")
+		upstream.mu.Unlock()
+		mu.Lock()
+		now = now.Add(9 * time.Second)
+		mu.Unlock()
+
+		addrs, err := resolver.LookupIPAddr(ctx, "a.test")
+		require.NoError(t, err)
+		assert.Equal(t, ipAddrs("10.0.0.1"), addrs)
+
+		eventually(t, func() bool {
+			addrs, err := resolver.LookupIPAddr(ctx, "a.test")
+			return err == nil && addrs[0].IP.Equal(net.ParseIP("10.0.0.2"))
+		})
+		assert.Equal(t, 2, upstream.Calls())
+	})
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type blackholeConn struct {
+	net.Conn
+	addr string
+}
+
+func TestDualStackDialer(t *testing.T) {
+	t.Run("expect fallback family to win when primary hangs", func(t *testing.T) {
+		upstream := &fakeResolver{addrs: map[string][]net.IPAddr{"a.test": ipAddrs("2001:db8::1", "10.0.0.1")}}
+		dialer := NewDualStackDialer(upstream).FallbackDelay(20 * time.Millisecond)
+		dialer.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if strings.HasPrefix(address, "[") {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return &blackholeConn{addr: address}, nil
+		}
+
+		started := time.Now()
+		conn, err := dialer.Dial("a.test:80")
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.1:80", conn.(*blackholeConn).addr)
+		assert.True(t, time.Since(started) < time.Second)
+	})
+
+	t.Run("expect fallback to start as soon as primary fails", func(t *testing.T) {
+		upstream := &fakeResolver{addrs: map[string][]net.IPAddr{"a.test": ipAddrs("10.0.0.1", "2001:db8::1")}}
+		dialer := NewDualStackDialer(upstream).FallbackDelay(time.Hour)
+		dialer.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if !strings.HasPrefix(address, "[") {
+				return nil, trifle.UnexpectedError()
+			}
+			return &blackholeConn{addr: address}, nil
+		}
+
+		conn, err := dialer.Dial("a.test:80")
+		require.NoError(t, err)
+		assert.Equal(t, "[2001:db8::1]:80", conn.(*blackholeConn).addr)
+	})
+
+	t.Run("expect error when every address fails", func(t *testing.T) {
+		expErr := trifle.UnexpectedError()
+		upstream := &fakeResolver{addrs: map[string][]net.IPAddr{"a.test": ipAddrs("10.0.0.1", "2001:db8::1")}}
+		dialer := NewDualStackDialer(upstream)
+		dialer.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, expErr
+		}
+		_, err := dialer.Dial("a.test:80")
+		assert.Equal(t, expErr, err)
+	})
+
+	t.Run("expect factory to resolve through injected resolver", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString("OK")
+		})
+		defer fx.Finish()
+		_, port, err := net.SplitHostPort(fixtureHost(fx))
+		require.NoError(t, err)
+
+		upstream := &fakeResolver{addrs: map[string][]net.IPAddr{"service.test": ipAddrs("127.0.0.1")}}
+		factory := NewFactory().Resolver(NewCachingResolver(upstream))
+		for i := 0; i < 2; i++ {
+			res, err := factory.To("http://service.test:"+port).GET("/").Header("Connection", "close").Go()
+			require.NoError(t, err)
+			assert.Equal(t, "OK", string(res.Body()))
+		}
+		assert.Equal(t, 1, upstream.Calls())
+	})
+}
@@ -3,12 +3,43 @@ package refasthttp
 import (
 	"github.com/remicro/api/net/rehttp"
 	"github.com/valyala/fasthttp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
 )
 
+type Response interface {
+	rehttp.Response
+	Headers() (headers map[string][]string)
+	ContentLength() (length int)
+	Date() (date time.Time, ok bool)
+	LastModified() (modified time.Time, ok bool)
+	CacheControl() (cacheControl CacheControl)
+	Cookies() (cookies []*fasthttp.Cookie)
+	Redirects() (hops []RedirectHop)
+	Timing() (timing Timing)
+}
+
+type CacheControl struct {
+	MaxAge         time.Duration
+	SMaxAge        time.Duration
+	NoCache        bool
+	NoStore        bool
+	NoTransform    bool
+	MustRevalidate bool
+	Private        bool
+	Public         bool
+	Immutable      bool
+	Directives     map[string]string
+}
+
 type responseImpl struct {
 	response      *fasthttp.Response
 	acquiredError error
 	decodedObject interface{}
+	redirects     []RedirectHop
+	timing        Timing
 }
 
 func (res *responseImpl) Status() (code int) {
@@ -28,13 +59,100 @@ func (res *responseImpl) ContentType() (contentType rehttp.ContentType) {
 }
 
 func (res *responseImpl) Header(key string) (values []string) {
-	value := res.response.Header.Peek(key)
-	if len(value) > 0 {
-		values = append(values, string(value))
-	}
+	res.response.Header.VisitAll(func(k, v []byte) {
+		if strings.EqualFold(key, string(k)) {
+			values = append(values, string(v))
+		}
+	})
+	return
+}
+
+func (res *responseImpl) Headers() (headers map[string][]string) {
+	headers = make(map[string][]string, res.response.Header.Len())
+	res.response.Header.VisitAll(func(k, v []byte) {
+		key := textproto.CanonicalMIMEHeaderKey(string(k))
+		headers[key] = append(headers[key], string(v))
+	})
 	return
 }
 
+func (res *responseImpl) ContentLength() (length int) {
+	return res.response.Header.ContentLength()
+}
+
+func (res *responseImpl) Date() (date time.Time, ok bool) {
+	return res.headerTime(fasthttp.HeaderDate)
+}
+
+func (res *responseImpl) LastModified() (modified time.Time, ok bool) {
+	return res.headerTime(fasthttp.HeaderLastModified)
+}
+
+func (res *responseImpl) CacheControl() (cacheControl CacheControl) {
+	return parseCacheControl(res.Header(fasthttp.HeaderCacheControl))
+}
+
+func (res *responseImpl) Timing() (timing Timing) {
+	return res.timing
+}
+
 func (res *responseImpl) Error() (err error) {
 	return res.acquiredError
 }
+
+func (res *responseImpl) headerTime(key string) (value time.Time, ok bool) {
+	raw := res.response.Header.Peek(key)
+	if len(raw) == 0 {
+		return
+	}
+	value, err := fasthttp.ParseHTTPDate(raw)
+	return value, err == nil
+}
+
+func parseCacheControl(values []string) (cacheControl CacheControl) {
+	cacheControl.Directives = make(map[string]string)
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			name, arg := directive, ""
+			if i := strings.IndexByte(directive, '='); i >= 0 {
+				name, arg = directive[:i], strings.Trim(directive[i+1:], `"`)
+			}
+			name = strings.ToLower(name)
+			cacheControl.Directives[name] = arg
+
+			switch name {
+			case "max-age":
+				cacheControl.MaxAge = parseSeconds(arg)
+			case "s-maxage":
+				cacheControl.SMaxAge = parseSeconds(arg)
+			case "no-cache":
+				cacheControl.NoCache = true
+			case "no-store":
+				cacheControl.NoStore = true
+			case "no-transform":
+				cacheControl.NoTransform = true
+			case "must-revalidate":
+				cacheControl.MustRevalidate = true
+			case "private":
+				cacheControl.Private = true
+			case "public":
+				cacheControl.Public = true
+			case "immutable":
+				cacheControl.Immutable = true
+			}
+		}
+	}
+	return
+}
+
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
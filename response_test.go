@@ -0,0 +1,89 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/remicro/trifle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"testing"
+	"time"
+)
+
+func TestResponseImpl_Header(t *testing.T) {
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Add("Link", "</a>; rel=next")
+		ctx.Response.Header.Add("Link", "</b>; rel=prev")
+		ctx.Response.Header.Set("X-Custom-Value", "value")
+		ctx.Write([]byte("OK"))
+	})
+	defer fx.Finish()
+
+	res, err := New().
+		Address(fx.Address()).
+		GET("/").
+		Go()
+	require.NoError(t, err)
+
+	t.Run("expect all values of repeated header", func(t *testing.T) {
+		assert.Equal(t, []string{"</a>; rel=next", "</b>; rel=prev"}, res.Header("Link"))
+	})
+
+	t.Run("expect case insensitive lookup", func(t *testing.T) {
+		assert.Equal(t, []string{"value"}, res.Header("x-custom-VALUE"))
+	})
+
+	t.Run("expect no values for unknown header", func(t *testing.T) {
+		assert.Empty(t, res.Header(trifle.String()))
+	})
+
+	t.Run("expect canonicalized headers map", func(t *testing.T) {
+		headers := res.(Response).Headers()
+		assert.Equal(t, []string{"</a>; rel=next", "</b>; rel=prev"}, headers["Link"])
+		assert.Equal(t, []string{"value"}, headers["X-Custom-Value"])
+		assert.Equal(t, []string{"2"}, headers["Content-Length"])
+	})
+}
+
+func TestResponseImpl_ParsedHeaders(t *testing.T) {
+	modified := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.SetLastModified(modified)
+		ctx.Response.Header.Set("Cache-Control", `public, max-age=60, s-maxage="120"`)
+		ctx.Response.Header.Add("Cache-Control", "must-revalidate")
+		ctx.Write([]byte("OK"))
+	})
+	defer fx.Finish()
+
+	res, err := New().
+		Address(fx.Address()).
+		GET("/").
+		Go()
+	require.NoError(t, err)
+	response := res.(Response)
+
+	t.Run("expect content length", func(t *testing.T) {
+		assert.Equal(t, 2, response.ContentLength())
+	})
+
+	t.Run("expect date", func(t *testing.T) {
+		date, ok := response.Date()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now(), date, time.Minute)
+	})
+
+	t.Run("expect last modified", func(t *testing.T) {
+		lastModified, ok := response.LastModified()
+		require.True(t, ok)
+		assert.True(t, modified.Equal(lastModified))
+	})
+
+	t.Run("expect cache control", func(t *testing.T) {
+		cacheControl := response.CacheControl()
+		assert.True(t, cacheControl.Public)
+		assert.True(t, cacheControl.MustRevalidate)
+		assert.False(t, cacheControl.NoStore)
+		assert.Equal(t, time.Minute, cacheControl.MaxAge)
+		assert.Equal(t, 2*time.Minute, cacheControl.SMaxAge)
+	})
+}
@@ -0,0 +1,142 @@
+package refasthttp
+
+import (
+	"errors"
+	"github.com/valyala/fasthttp"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultThrottleK      = 2
+	defaultThrottleWindow = 2 * time.Minute
+	throttleSlots         = 12
+)
+
+var (
+	ErrThrottled = errors.New("request throttled by the client")
+)
+
+// ThrottlePolicy configures adaptive throttling: once a service answers
+// with 429 or 503 the client rejects requests locally with probability
+// max(0, (requests - K * accepts) / (requests + 1)) over the Window, so the
+// load it sends stays close to K times what the service accepts. Any other
+// response counts as accepted, requests failing without a response, such
+// as refused connections and timeouts, are left out of the ratio.
+type ThrottlePolicy struct {
+	// K is 2 when zero, lower values throttle more aggressively.
+	K float64
+	// Window is 2 minutes when zero.
+	Window time.Duration
+}
+
+// AdaptiveThrottling tracks attempted and accepted requests per service,
+// or per host for requests without a service, and fails the rejected ones
+// with ErrThrottled.
+func (f *Factory) AdaptiveThrottling(policy ThrottlePolicy) *Factory {
+	if policy.K <= 0 {
+		policy.K = defaultThrottleK
+	}
+	if policy.Window <= 0 {
+		policy.Window = defaultThrottleWindow
+	}
+	f.throttling = &throttling{
+		policy:   policy,
+		now:      time.Now,
+		random:   rand.Float64,
+		counters: make(map[string]*throttleCounters),
+	}
+	return f
+}
+
+type throttling struct {
+	policy ThrottlePolicy
+	now    func() time.Time
+	random func() float64
+
+	mu       sync.Mutex
+	counters map[string]*throttleCounters
+}
+
+// throttleCounters splits the window into slots so old counts expire.
+type throttleCounters struct {
+	slots [throttleSlots]throttleSlot
+}
+
+type throttleSlot struct {
+	index    int64
+	requests float64
+	accepts  float64
+}
+
+func (t *throttling) slot(key string) *throttleSlot {
+	counters, ok := t.counters[key]
+	if !ok {
+		counters = &throttleCounters{}
+		t.counters[key] = counters
+	}
+	index := t.now().UnixNano() / int64(t.policy.Window/throttleSlots)
+	slot := &counters.slots[index%throttleSlots]
+	if slot.index != index {
+		*slot = throttleSlot{index: index}
+	}
+	return slot
+}
+
+// probability returns the rejection probability of key.
+func (t *throttling) probability(key string) float64 {
+	counters, ok := t.counters[key]
+	if !ok {
+		return 0
+	}
+	current := t.slot(key).index
+	var requests, accepts float64
+	for _, slot := range counters.slots {
+		if current-slot.index < throttleSlots {
+			requests += slot.requests
+			accepts += slot.accepts
+		}
+	}
+	return math.Max(0, (requests-t.policy.K*accepts)/(requests+1))
+}
+
+// admit counts a request to key and decides whether it may be sent.
+func (t *throttling) admit(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.probability(key)
+	t.slot(key).requests++
+	return p == 0 || t.random() >= p
+}
+
+func (t *throttling) record(key string, accepted bool) {
+	if !accepted {
+		return
+	}
+	t.mu.Lock()
+	t.slot(key).accepts++
+	t.mu.Unlock()
+}
+
+// forget takes back a request to key admitted without getting a response.
+func (t *throttling) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if slot := t.slot(key); slot.requests > 0 {
+		slot.requests--
+	}
+}
+
+func overloaded(status int) bool {
+	return status == fasthttp.StatusTooManyRequests || status == fasthttp.StatusServiceUnavailable
+}
+
+// limitKey is the service of the request or its host.
+func (fhc *fastHttpClient) limitKey() string {
+	if fhc.service != "" {
+		return fhc.service
+	}
+	return string(fhc.uri.Host())
+}
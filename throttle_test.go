@@ -0,0 +1,94 @@
+package refasthttp
+
+import (
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFactory_AdaptiveThrottling(t *testing.T) {
+	var hits int32
+	var status int32 = fasthttp.StatusOK
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&hits, 1)
+		ctx.SetStatusCode(int(atomic.LoadInt32(&status)))
+	})
+	defer fx.Finish()
+	bln := reFastHttpFixture.Balancer(map[string][]string{"service": {fx.Address()}})
+
+	t.Run("expect healthy service never to be throttled", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		factory := NewFactory().Balancer(bln).AdaptiveThrottling(ThrottlePolicy{})
+		factory.throttling.random = func() float64 { return 0 }
+		for i := 0; i < 10; i++ {
+			_, err := factory.Service("service").GET("/").Go()
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(10), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("expect local rejections while service is overloaded", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		atomic.StoreInt32(&status, fasthttp.StatusServiceUnavailable)
+		defer atomic.StoreInt32(&status, fasthttp.StatusOK)
+		factory := NewFactory().Balancer(bln).AdaptiveThrottling(ThrottlePolicy{})
+		factory.throttling.random = func() float64 { return 0.6 }
+
+		var throttled int
+		for i := 0; i < 10; i++ {
+			res, err := factory.Service("service").GET("/").Go()
+			if err == ErrThrottled {
+				throttled++
+				continue
+			}
+			require.NoError(t, err)
+			assert.Equal(t, fasthttp.StatusServiceUnavailable, res.Status())
+		}
+		assert.Equal(t, 8, throttled)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("expect only 429 and 503 to count as rejections", func(t *testing.T) {
+		factory := NewFactory().AdaptiveThrottling(ThrottlePolicy{})
+		factory.throttling.random = func() float64 { return 0 }
+		atomic.StoreInt32(&status, fasthttp.StatusInternalServerError)
+		for i := 0; i < 5; i++ {
+			_, err := factory.To(fx.Address()).GET("/").Go()
+			require.NoError(t, err)
+		}
+		atomic.StoreInt32(&status, fasthttp.StatusOK)
+		closed := closedAddress(t)
+		for i := 0; i < 5; i++ {
+			_, err := factory.To(closed).GET("/").Go()
+			require.Error(t, err)
+		}
+
+		factory.throttling.mu.Lock()
+		defer factory.throttling.mu.Unlock()
+		assert.Equal(t, 0.0, factory.throttling.probability(fixtureHost(fx)))
+		slot := factory.throttling.slot(fixtureHost(fx))
+		assert.Equal(t, 5.0, slot.requests)
+		assert.Equal(t, 5.0, slot.accepts)
+		assert.Equal(t, 0.0, factory.throttling.slot(closed[len("http://"):]).requests)
+	})
+}
+
+func TestThrottling_Window(t *testing.T) {
+	now := time.Now()
+	factory := NewFactory().AdaptiveThrottling(ThrottlePolicy{K: 1, Window: time.Minute})
+	throttling := factory.throttling
+	throttling.now = func() time.Time { return now }
+
+	for i := 0; i < 9; i++ {
+		throttling.slot("service").requests++
+	}
+	throttling.record("service", true)
+	assert.Equal(t, 0.8, throttling.probability("service"))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, 0.0, throttling.probability("service"))
+}
@@ -0,0 +1,137 @@
+package refasthttp
+
+import (
+	"github.com/valyala/fasthttp"
+	"net"
+	"time"
+)
+
+// Timing breaks the latency of a call down into phases, summed over the
+// attempts of the call:
+//
+//	Queue      waiting for a free connection of the pool
+//	DNS        resolving the host, known when the factory has a Resolver and
+//	           part of Connect otherwise
+//	Connect    dialing, including proxy handshakes
+//	TLS        the TLS handshake
+//	FirstByte  from writing the request to the first byte of the response
+//	BodyRead   from the first byte to the end of the response
+//	Decode     decoding the response body
+//
+// DNS, Connect and TLS are zero when the connection was Reused from the
+// pool, a connection dialed after the attempt started counts as dialed for
+// it. Responses shared by coalescing and pipelined requests, and requests
+// over unix sockets, only report Decode and Total, the latency of Go.
+type Timing struct {
+	Queue     time.Duration
+	DNS       time.Duration
+	Connect   time.Duration
+	TLS       time.Duration
+	FirstByte time.Duration
+	BodyRead  time.Duration
+	Decode    time.Duration
+	Total     time.Duration
+	Reused    bool
+}
+
+// timedDial is a dial function reporting how long resolving the host took.
+type timedDial func(addr string) (conn net.Conn, resolving time.Duration, err error)
+
+func untimed(dial fasthttp.DialFunc) timedDial {
+	return func(addr string) (net.Conn, time.Duration, error) {
+		conn, err := dial(addr)
+		return conn, 0, err
+	}
+}
+
+// exchanges is how many exchanges a tracked connection remembers, enough
+// for the TLS handshake and the request sent after it.
+const exchanges = 4
+
+// exchange is a run of writes to a tracked connection and the first read
+// after it, the request and the response once a TLS handshake is over.
+type exchange struct {
+	written   time.Time
+	firstByte time.Time
+}
+
+func (tc *trackedConn) Write(b []byte) (int, error) {
+	now := time.Now()
+	tc.mu.Lock()
+	if tc.reading || len(tc.exchanges) == 0 {
+		tc.reading = false
+		if len(tc.exchanges) == exchanges {
+			tc.exchanges = append(tc.exchanges[:0], tc.exchanges[1:]...)
+		}
+		tc.exchanges = append(tc.exchanges, exchange{written: now})
+	}
+	tc.mu.Unlock()
+	return tc.Conn.Write(b)
+}
+
+func (tc *trackedConn) Read(b []byte) (int, error) {
+	n, err := tc.Conn.Read(b)
+	if n > 0 {
+		now := time.Now()
+		tc.mu.Lock()
+		if !tc.reading && len(tc.exchanges) > 0 {
+			tc.reading = true
+			tc.exchanges[len(tc.exchanges)-1].firstByte = now
+		}
+		tc.mu.Unlock()
+	}
+	return n, err
+}
+
+// observe adds the phases of the attempt started at sent whose response is
+// resp, which is done by now. The connection of the response is found in
+// the pool by its local address and https tells whether it did a TLS
+// handshake.
+func (t *Timing) observe(p *pool, sent time.Time, https bool, resp *fasthttp.Response) {
+	done := time.Now()
+	tc := p.conn(resp.LocalAddr())
+	if tc == nil {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	var ex exchange
+	for i := len(tc.exchanges) - 1; i >= 0; i-- {
+		candidate := tc.exchanges[i]
+		if candidate.written.Before(sent) {
+			break
+		}
+		if !candidate.firstByte.IsZero() && !candidate.firstByte.After(done) {
+			ex = candidate
+			break
+		}
+	}
+	if ex.written.IsZero() {
+		return
+	}
+	fresh := !tc.dialStarted.Before(sent)
+	t.Reused = !fresh
+	if fresh {
+		t.Queue += tc.dialStarted.Sub(sent)
+		t.DNS += tc.resolving
+		t.Connect += tc.dialed.Sub(tc.dialStarted) - tc.resolving
+		if https {
+			t.TLS += ex.written.Sub(tc.dialed)
+		}
+	} else {
+		t.Queue += ex.written.Sub(sent)
+	}
+	t.FirstByte += ex.firstByte.Sub(ex.written)
+	t.BodyRead += done.Sub(ex.firstByte)
+}
+
+func (t *Timing) add(other Timing) {
+	t.Queue += other.Queue
+	t.DNS += other.DNS
+	t.Connect += other.Connect
+	t.TLS += other.TLS
+	t.FirstByte += other.FirstByte
+	t.BodyRead += other.BodyRead
+	t.Decode += other.Decode
+	t.Reused = other.Reused
+}
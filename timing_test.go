@@ -0,0 +1,144 @@
+package refasthttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"github.com/remicro/api/net/rehttp"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type slowResolver struct {
+	Resolver
+	delay time.Duration
+}
+
+func (sr slowResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	time.Sleep(sr.delay)
+	return sr.Resolver.LookupIPAddr(ctx, host)
+}
+
+func TestFastHttpClient_Timing(t *testing.T) {
+	const delay = 30 * time.Millisecond
+	handler := func(ctx *fasthttp.RequestCtx) {
+		time.Sleep(delay)
+		ctx.SetContentType("application/json")
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			w.WriteString(`{"head":"`)
+			w.Flush()
+			time.Sleep(delay)
+			w.WriteString(strings.Repeat("x", 64) + `"}`)
+		})
+	}
+
+	t.Run("expect phases of fresh and reused connections", func(t *testing.T) {
+		certs := reFastHttpFixture.NewCertificates(t)
+		fx := reFastHttpFixture.NewTLS(t, certs, tls.NoClientCert, handler)
+		defer fx.Finish()
+		config, err := NewTLS().RootCA(certs.CAFile).Build()
+		require.NoError(t, err)
+		resolver := slowResolver{
+			Resolver: &fakeResolver{addrs: map[string][]net.IPAddr{"localhost": ipAddrs("127.0.0.1")}},
+			delay:    delay,
+		}
+		factory := NewFactory().TLS(config).Resolver(resolver)
+		address := "https://localhost:" + fx.Address()[strings.LastIndex(fx.Address(), ":")+1:]
+
+		var decoded map[string]string
+		res, err := factory.To(address).GET("/").
+			DecodeType(rehttp.ContentTypeJson).
+			Decoder(reFastHttpFixture.Decoder()).
+			ToDecode(&decoded).
+			Go()
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat("x", 64), decoded["head"])
+		timing := res.(Response).Timing()
+		assert.False(t, timing.Reused)
+		assert.True(t, timing.DNS >= delay, "dns %s", timing.DNS)
+		assert.True(t, timing.Connect > 0)
+		assert.True(t, timing.TLS > 0)
+		assert.True(t, timing.FirstByte >= delay, "first byte %s", timing.FirstByte)
+		assert.True(t, timing.BodyRead >= delay, "body read %s", timing.BodyRead)
+		assert.True(t, timing.Decode > 0)
+		assert.True(t, timing.Total >= timing.DNS+timing.Connect+timing.TLS+timing.FirstByte+timing.BodyRead+timing.Decode)
+
+		res, err = factory.To(address).GET("/").Go()
+		require.NoError(t, err)
+		timing = res.(Response).Timing()
+		assert.True(t, timing.Reused)
+		assert.Zero(t, timing.DNS)
+		assert.Zero(t, timing.Connect)
+		assert.Zero(t, timing.TLS)
+		assert.True(t, timing.FirstByte >= delay)
+		assert.True(t, timing.BodyRead >= delay)
+	})
+
+	fx := reFastHttpFixture.New(t, handler)
+	defer fx.Finish()
+
+	t.Run("expect wait for a pooled connection to be queue time", func(t *testing.T) {
+		factory := NewFactory().Pool(PoolConfig{MaxConnsPerHost: 1, MaxConnWaitTimeout: time.Second})
+		first := factory.To(fx.Address()).GET("/").(Builder).GoAsync()
+		eventually(t, func() bool {
+			return factory.Stats()[fixtureHost(fx)].InUse == 1
+		})
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		_, err = first.Wait()
+		require.NoError(t, err)
+
+		timing := res.(Response).Timing()
+		assert.True(t, timing.Reused)
+		assert.True(t, timing.Queue >= delay, "queue %s", timing.Queue)
+	})
+
+	t.Run("expect phases of a connection closed by the response", func(t *testing.T) {
+		closing := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+			handler(ctx)
+			ctx.SetConnectionClose()
+		})
+		defer closing.Finish()
+		factory := NewFactory()
+		for i := 0; i < 2; i++ {
+			res, err := factory.To(closing.Address()).GET("/").Go()
+			require.NoError(t, err)
+			timing := res.(Response).Timing()
+			assert.False(t, timing.Reused)
+			assert.True(t, timing.Connect > 0)
+			assert.True(t, timing.FirstByte >= delay, "first byte %s", timing.FirstByte)
+		}
+	})
+
+	t.Run("expect timing to reach access log and metrics", func(t *testing.T) {
+		logger := &recordLogger{}
+		metrics := &recordMetrics{}
+		factory := NewFactory().Logger(logger).Metrics(metrics).AccessLog(AccessLogConfig{Timing: true})
+		_, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+
+		entry := logger.Entries()[0]
+		assert.True(t, entry.Fields["first_byte"].(time.Duration) >= delay)
+		assert.True(t, entry.Fields["body_read"].(time.Duration) >= delay)
+		assert.Equal(t, false, entry.Fields["reused"])
+		require.Len(t, metrics.results, 1)
+		assert.True(t, metrics.results[0].Timing.FirstByte >= delay)
+		assert.Equal(t, metrics.results[0].Latency, metrics.results[0].Timing.Total)
+	})
+}
+
+type recordMetrics struct {
+	results []CallResult
+}
+
+func (rm *recordMetrics) Started(labels CallLabels) {}
+
+func (rm *recordMetrics) Finished(labels CallLabels, result CallResult) {
+	rm.results = append(rm.results, result)
+}
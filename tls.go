@@ -0,0 +1,171 @@
+package refasthttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoRootCertificates  = errors.New("no certificates found in root CA file")
+	ErrNoClientCertificate = errors.New("no client certificate matches the server request")
+)
+
+// TLSBuilder assembles the tls.Config shared by the client of a Factory.
+type TLSBuilder struct {
+	rootCAFiles  []string
+	certificates []*certificateReloader
+	serverName   string
+	minVersion   uint16
+	cipherSuites []uint16
+}
+
+func NewTLS() *TLSBuilder {
+	return &TLSBuilder{
+		minVersion: tls.VersionTLS12,
+	}
+}
+
+// RootCA replaces the system roots with the certificates from the given PEM files.
+func (tb *TLSBuilder) RootCA(pemFiles ...string) *TLSBuilder {
+	tb.rootCAFiles = append(tb.rootCAFiles, pemFiles...)
+	return tb
+}
+
+// ClientCertificate adds a key pair presented for mutual TLS. The files are
+// re-read whenever they change on disk, so rotated certificates are picked
+// up by the next handshake.
+func (tb *TLSBuilder) ClientCertificate(certFile, keyFile string) *TLSBuilder {
+	tb.certificates = append(tb.certificates, &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	})
+	return tb
+}
+
+func (tb *TLSBuilder) ServerName(name string) *TLSBuilder {
+	tb.serverName = name
+	return tb
+}
+
+func (tb *TLSBuilder) MinVersion(version uint16) *TLSBuilder {
+	tb.minVersion = version
+	return tb
+}
+
+// CipherSuites restricts the cipher suites offered for TLS 1.2 and below.
+func (tb *TLSBuilder) CipherSuites(suites ...uint16) *TLSBuilder {
+	tb.cipherSuites = suites
+	return tb
+}
+
+func (tb *TLSBuilder) Build() (config *tls.Config, err error) {
+	config = &tls.Config{
+		ServerName:   tb.serverName,
+		MinVersion:   tb.minVersion,
+		CipherSuites: tb.cipherSuites,
+	}
+	if len(tb.rootCAFiles) > 0 {
+		config.RootCAs = x509.NewCertPool()
+		for _, file := range tb.rootCAFiles {
+			var data []byte
+			data, err = ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if !config.RootCAs.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("%s: %w", file, ErrNoRootCertificates)
+			}
+		}
+	}
+	if len(tb.certificates) > 0 {
+		for _, reloader := range tb.certificates {
+			if _, err = reloader.certificate(); err != nil {
+				return nil, err
+			}
+		}
+		certificates := tb.certificates
+		config.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return clientCertificate(certificates, info)
+		}
+	}
+	return
+}
+
+func (f *Factory) TLS(config *tls.Config) *Factory {
+	f.tls = config
+	f.client.TLSConfig = config
+	return f
+}
+
+func clientCertificate(reloaders []*certificateReloader, info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	var lastErr error
+	for _, reloader := range reloaders {
+		cert, err := reloader.certificate()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if info.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNoClientCertificate
+}
+
+type certificateReloader struct {
+	mu       sync.Mutex
+	certFile string
+	keyFile  string
+	modTime  time.Time
+	cert     *tls.Certificate
+}
+
+// certificate returns the cached key pair, reloading it when either file has
+// been modified. A failed reload keeps serving the previous pair.
+func (cr *certificateReloader) certificate() (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	modTime, err := latestModTime(cr.certFile, cr.keyFile)
+	if err != nil {
+		if cr.cert != nil {
+			return cr.cert, nil
+		}
+		return nil, err
+	}
+	if cr.cert != nil && modTime.Equal(cr.modTime) {
+		return cr.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		if cr.cert != nil {
+			return cr.cert, nil
+		}
+		return nil, err
+	}
+	cr.cert, cr.modTime = &cert, modTime
+	return cr.cert, nil
+}
+
+func latestModTime(files ...string) (latest time.Time, err error) {
+	for _, file := range files {
+		var info os.FileInfo
+		info, err = os.Stat(file)
+		if err != nil {
+			return
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return
+}
@@ -0,0 +1,123 @@
+package refasthttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func peerCommonName(ctx *fasthttp.RequestCtx) {
+	ctx.SetConnectionClose()
+	state := ctx.TLSConnectionState()
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+	ctx.WriteString(state.PeerCertificates[0].Subject.CommonName)
+}
+
+func TestTLSBuilder_Build(t *testing.T) {
+	certs := reFastHttpFixture.NewCertificates(t)
+
+	t.Run("expect private root CA to be trusted", func(t *testing.T) {
+		fx := reFastHttpFixture.NewTLS(t, certs, tls.NoClientCert, peerCommonName)
+		defer fx.Finish()
+
+		config, err := NewTLS().RootCA(certs.CAFile).Build()
+		require.NoError(t, err)
+		res, err := NewFactory().TLS(config).To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status())
+	})
+
+	t.Run("expect unknown authority to be rejected", func(t *testing.T) {
+		fx := reFastHttpFixture.NewTLS(t, certs, tls.NoClientCert, peerCommonName)
+		defer fx.Finish()
+
+		res, err := NewFactory().To(fx.Address()).GET("/").Go()
+		require.Error(t, err)
+		assert.Nil(t, res)
+	})
+
+	t.Run("expect server name override", func(t *testing.T) {
+		fx := reFastHttpFixture.NewTLS(t, certs, tls.NoClientCert, peerCommonName)
+		defer fx.Finish()
+
+		config, err := NewTLS().RootCA(certs.CAFile).ServerName("localhost").Build()
+		require.NoError(t, err)
+		_, err = NewFactory().TLS(config).To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+
+		config, err = NewTLS().RootCA(certs.CAFile).ServerName("example.com").Build()
+		require.NoError(t, err)
+		_, err = NewFactory().TLS(config).To(fx.Address()).GET("/").Go()
+		require.Error(t, err)
+	})
+
+	t.Run("expect client certificate for mutual TLS", func(t *testing.T) {
+		fx := reFastHttpFixture.NewTLS(t, certs, tls.RequireAndVerifyClientCert, peerCommonName)
+		defer fx.Finish()
+
+		config, err := NewTLS().
+			RootCA(certs.CAFile).
+			ClientCertificate(certs.ClientCertFile, certs.ClientKeyFile).
+			Build()
+		require.NoError(t, err)
+		res, err := NewFactory().TLS(config).To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "client", string(res.Body()))
+
+		config, err = NewTLS().RootCA(certs.CAFile).Build()
+		require.NoError(t, err)
+		_, err = NewFactory().TLS(config).To(fx.Address()).GET("/").Go()
+		require.Error(t, err)
+	})
+
+	t.Run("expect rotated client certificate to be reloaded", func(t *testing.T) {
+		fx := reFastHttpFixture.NewTLS(t, certs, tls.RequireAndVerifyClientCert, peerCommonName)
+		defer fx.Finish()
+
+		certFile, keyFile := certs.Issue("before-rotation")
+		config, err := NewTLS().
+			RootCA(certs.CAFile).
+			ClientCertificate(certFile, keyFile).
+			Build()
+		require.NoError(t, err)
+		factory := NewFactory().TLS(config)
+
+		res, err := factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "before-rotation", string(res.Body()))
+
+		rotatedCert, rotatedKey := certs.Issue("after-rotation")
+		copyFile(t, rotatedCert, certFile)
+		copyFile(t, rotatedKey, keyFile)
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(certFile, future, future))
+
+		res, err = factory.To(fx.Address()).GET("/").Go()
+		require.NoError(t, err)
+		assert.Equal(t, "after-rotation", string(res.Body()))
+	})
+
+	t.Run("expect error on missing files", func(t *testing.T) {
+		_, err := NewTLS().RootCA(certs.Dir + "/missing.pem").Build()
+		assert.Error(t, err)
+		_, err = NewTLS().ClientCertificate(certs.Dir+"/missing.pem", certs.ClientKeyFile).Build()
+		assert.Error(t, err)
+		_, err = NewTLS().RootCA(certs.ClientKeyFile).Build()
+		assert.True(t, errors.Is(err, ErrNoRootCertificates))
+	})
+}
+
+func copyFile(t *testing.T, from, to string) {
+	data, err := ioutil.ReadFile(from)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(to, data, 0600))
+}
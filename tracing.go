@@ -0,0 +1,170 @@
+package refasthttp
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/remicro/api/net/rehttp"
+	"github.com/valyala/fasthttp"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+	HeaderBaggage     = "baggage"
+)
+
+// Tracer starts client spans. Start returns a child of the span carried by
+// ctx, or a root span when there is none, and a context carrying the new
+// span. Hedged requests start spans concurrently.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is what the client needs of a span, so that OpenTelemetry or any
+// other tracer can back it.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// SpanContext is propagated in the traceparent and tracestate headers.
+// Nothing is propagated when TraceID is zero.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Sampled    bool
+	TraceState string
+}
+
+// Traceparent formats sc as a version 00 traceparent header.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// Tracing starts a span per call and a child span per attempt sent, that is
+// per hedge and per redirect followed. The parent is taken from the Context
+// of the builder. Attempts carry the traceparent, tracestate and baggage
+// headers.
+func (f *Factory) Tracing(tracer Tracer) *Factory {
+	f.tracer = tracer
+	return f
+}
+
+type baggageKey struct{}
+
+// ContextWithBaggage adds a member to the baggage sent by calls made with
+// the returned context.
+func ContextWithBaggage(ctx context.Context, key, value string) context.Context {
+	members := make(map[string]string)
+	for k, v := range BaggageFromContext(ctx) {
+		members[k] = v
+	}
+	members[key] = value
+	return context.WithValue(ctx, baggageKey{}, members)
+}
+
+func BaggageFromContext(ctx context.Context) map[string]string {
+	members, _ := ctx.Value(baggageKey{}).(map[string]string)
+	return members
+}
+
+func formatBaggage(members map[string]string) string {
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = key + "=" + url.PathEscape(members[key])
+	}
+	return strings.Join(keys, ",")
+}
+
+func (fhc *fastHttpClient) spanName() string {
+	return string(fhc.req.Header.Method()) + " " + fhc.pathTemplate()
+}
+
+func (fhc *fastHttpClient) startCall(tracer Tracer) Span {
+	parent := fhc.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	var span Span
+	fhc.traceCtx, span = tracer.Start(parent, fhc.spanName())
+	span.SetAttribute("http.method", string(fhc.req.Header.Method()))
+	span.SetAttribute("http.route", fhc.pathTemplate())
+	if fhc.service != "" {
+		span.SetAttribute("peer.service", fhc.service)
+	}
+	return span
+}
+
+func (fhc *fastHttpClient) endCall(span Span, response rehttp.Response, err error) {
+	span.SetAttribute("http.url", fhc.factory.redaction.url(fhc.uri))
+	span.SetAttribute("http.attempts", fhc.attempts)
+	if response != nil {
+		span.SetAttribute("http.status_code", response.Status())
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// startAttempt starts the span of an attempt and propagates it.
+func (fhc *fastHttpClient) startAttempt(tracer Tracer) Span {
+	_, span := tracer.Start(fhc.traceCtx, fhc.spanName())
+	span.SetAttribute("http.method", string(fhc.req.Header.Method()))
+	span.SetAttribute("http.url", fhc.factory.redaction.url(fhc.uri))
+	span.SetAttribute("http.resend_count", fhc.attempts-1)
+	if fhc.socket != "" {
+		span.SetAttribute("net.sock.peer.addr", fhc.socket)
+	} else {
+		span.SetAttribute("net.peer.name", string(fhc.uri.Host()))
+		if port := peerPort(fhc.uri); port != 0 {
+			span.SetAttribute("net.peer.port", port)
+		}
+	}
+
+	if sc := span.SpanContext(); sc.TraceID != ([16]byte{}) {
+		fhc.req.Header.Set(HeaderTraceparent, sc.Traceparent())
+		if sc.TraceState != "" {
+			fhc.req.Header.Set(HeaderTracestate, sc.TraceState)
+		}
+	}
+	if members := BaggageFromContext(fhc.traceCtx); len(members) > 0 {
+		fhc.req.Header.Set(HeaderBaggage, formatBaggage(members))
+	}
+	return span
+}
+
+func endAttempt(span Span, resp *fasthttp.Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		span.SetAttribute("http.status_code", resp.StatusCode())
+	}
+	span.End()
+}
+
+func peerPort(uri *fasthttp.URI) int {
+	host := string(uri.Host())
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+		port, _ := strconv.Atoi(host[i+1:])
+		return port
+	}
+	if string(uri.Scheme()) == "https" {
+		return 443
+	}
+	return 80
+}
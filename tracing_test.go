@@ -0,0 +1,168 @@
+package refasthttp
+
+import (
+	"context"
+	"errors"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type recordSpan struct {
+	name       string
+	parent     *recordSpan
+	sc         SpanContext
+	mu         sync.Mutex
+	attributes map[string]interface{}
+	errs       []error
+	ended      bool
+}
+
+func (s *recordSpan) SpanContext() SpanContext {
+	return s.sc
+}
+
+func (s *recordSpan) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *recordSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+}
+
+type spanKey struct{}
+
+type recordTracer struct {
+	mu    sync.Mutex
+	spans []*recordSpan
+}
+
+func (rt *recordTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	span := &recordSpan{name: name, attributes: make(map[string]interface{})}
+	span.sc.SpanID[7] = byte(len(rt.spans) + 1)
+	span.sc.TraceID[15] = 1
+	span.sc.Sampled = true
+	if parent, ok := ctx.Value(spanKey{}).(*recordSpan); ok {
+		span.parent = parent
+		span.sc.TraceID = parent.sc.TraceID
+		span.sc.TraceState = parent.sc.TraceState
+	}
+	rt.spans = append(rt.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (rt *recordTracer) Spans() []*recordSpan {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return append([]*recordSpan(nil), rt.spans...)
+}
+
+func TestFactory_Tracing(t *testing.T) {
+	fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == "/moved" {
+			ctx.Redirect("/users/7", fasthttp.StatusFound)
+			return
+		}
+		if status, err := strconv.Atoi(string(ctx.QueryArgs().Peek("status"))); err == nil {
+			ctx.SetStatusCode(status)
+		}
+		ctx.Write(ctx.Request.Header.Peek(HeaderTraceparent))
+		ctx.WriteString("|")
+		ctx.Write(ctx.Request.Header.Peek(HeaderTracestate))
+		ctx.WriteString("|")
+		ctx.Write(ctx.Request.Header.Peek(HeaderBaggage))
+	})
+	defer fx.Finish()
+	bln := reFastHttpFixture.Balancer(map[string][]string{"users": {fx.Address()}})
+
+	t.Run("expect call and attempt spans under the parent of the context", func(t *testing.T) {
+		tracer := &recordTracer{}
+		parentCtx, parent := tracer.Start(context.Background(), "handler")
+		parent.(*recordSpan).sc.TraceState = "vendor=1"
+		ctx := ContextWithBaggage(parentCtx, "tenant", "acme corp")
+		ctx = ContextWithBaggage(ctx, "region", "eu")
+
+		res, err := NewFactory().Balancer(bln).Tracing(tracer).
+			Service("users").
+			GET("/users/{id}").(Builder).
+			PathParam("id", "7").
+			AddQueryParam("api_key", "secret").
+			Context(ctx).
+			Go()
+		require.NoError(t, err)
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 3)
+		call, attempt := spans[1], spans[2]
+		assert.Equal(t, "GET /users/{id}", call.name)
+		assert.Equal(t, parent, call.parent)
+		assert.Equal(t, call, attempt.parent)
+		assert.Equal(t, "00-00000000000000000000000000000001-0000000000000003-01|vendor=1|region=eu,tenant=acme%20corp", string(res.Body()))
+		assert.Equal(t, "GET", call.attributes["http.method"])
+		assert.Equal(t, "/users/{id}", call.attributes["http.route"])
+		assert.Equal(t, "users", call.attributes["peer.service"])
+		assert.Equal(t, fx.Address()+"/users/7?api_key=REDACTED", call.attributes["http.url"])
+		assert.Equal(t, fasthttp.StatusOK, call.attributes["http.status_code"])
+		assert.Equal(t, 1, call.attributes["http.attempts"])
+		assert.Equal(t, fixtureHost(fx), attempt.attributes["net.peer.name"])
+		assert.Equal(t, 0, attempt.attributes["http.resend_count"])
+		assert.Equal(t, fasthttp.StatusOK, attempt.attributes["http.status_code"])
+		assert.True(t, call.ended)
+		assert.True(t, attempt.ended)
+	})
+
+	t.Run("expect span per redirect followed", func(t *testing.T) {
+		tracer := &recordTracer{}
+		res, err := NewFactory().Tracing(tracer).To(fx.Address()).
+			GET("/moved").(Builder).
+			Redirects(RedirectPolicy{}).
+			Go()
+		require.NoError(t, err)
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 3)
+		assert.Nil(t, spans[0].parent)
+		assert.Equal(t, fasthttp.StatusFound, spans[1].attributes["http.status_code"])
+		assert.Equal(t, 1, spans[2].attributes["http.resend_count"])
+		assert.Equal(t, spans[0], spans[2].parent)
+		assert.Contains(t, string(res.Body()), "-0000000000000003-01|")
+	})
+
+	t.Run("expect errors and statuses to be recorded", func(t *testing.T) {
+		tracer := &recordTracer{}
+		factory := NewFactory().Tracing(tracer)
+		_, err := factory.To(fx.Address()).GET("/").QueryParam("status", "503").Go()
+		require.NoError(t, err)
+		_, err = factory.To(closedAddress(t)).GET("/").Go()
+		require.Error(t, err)
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = factory.To(fx.Address()).GET("/").(Builder).Context(canceled).Go()
+		require.Error(t, err)
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 5)
+		assert.Equal(t, fasthttp.StatusServiceUnavailable, spans[0].attributes["http.status_code"])
+		assert.Empty(t, spans[0].errs)
+		assert.Len(t, spans[2].errs, 1)
+		assert.Len(t, spans[3].errs, 1)
+		assert.True(t, errors.Is(spans[4].errs[0], context.Canceled))
+		assert.Equal(t, 0, spans[4].attributes["http.attempts"])
+	})
+}
@@ -0,0 +1,143 @@
+package refasthttp
+
+import (
+	"github.com/remicro/api/cloud/discovery"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWarmupBudget = 5 * time.Second
+	// maxWarmupFinds bounds the Find calls which enumerate the nodes of a
+	// balancer that isn't a NodeLister
+	maxWarmupFinds = 64
+)
+
+// NodeLister is implemented by balancers which can list every node of a
+// service. Warmup asks other balancers for nodes with Find until one comes
+// back a second time, which reaches every node of a round robin balancer but
+// may miss some of a random one.
+type NodeLister interface {
+	Nodes(name string) (nodes []discovery.Node, err error)
+}
+
+// WarmupReport lists the node addresses which got their connections and
+// the error of every node which didn't.
+type WarmupReport struct {
+	Warmed []string
+	Failed map[string]error
+}
+
+// WarmupBudget bounds how long Warmup may take, 5 seconds by default.
+func (f *Factory) WarmupBudget(budget time.Duration) *Factory {
+	f.warmupBudget = budget
+	return f
+}
+
+// Warmup opens connsPerNode keep-alive connections to every node of
+// service by sending that many concurrent OPTIONS requests per node straight
+// to the transport, HEAD makes fasthttp servers close the connection. They
+// skip rate limiting, throttling, tracing, metrics and the cookie jar as
+// fasthttp only pools connections it sent a request over. The connections
+// stay idle in the pool for the traffic which follows. Any response counts
+// as success, nodes which fail or don't answer within the budget are
+// reported in Failed.
+func (f *Factory) Warmup(service string, connsPerNode int) (report WarmupReport, err error) {
+	nodes, err := f.warmupNodes(service)
+	if err != nil {
+		return
+	}
+	if connsPerNode < 1 {
+		connsPerNode = 1
+	}
+	budget := f.warmupBudget
+	if budget <= 0 {
+		budget = defaultWarmupBudget
+	}
+	deadline := time.Now().Add(budget)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := make(map[string]error)
+	for _, node := range nodes {
+		if f.pinning != nil {
+			f.pinning.bindService(node.Address(), service)
+		}
+		for i := 0; i < connsPerNode; i++ {
+			wg.Add(1)
+			go func(address string) {
+				defer wg.Done()
+				warmErr := f.warmConn(address, deadline)
+				if warmErr == nil {
+					return
+				}
+				mu.Lock()
+				if _, ok := failed[address]; !ok {
+					failed[address] = warmErr
+				}
+				mu.Unlock()
+			}(node.Address())
+		}
+	}
+	wg.Wait()
+
+	report.Failed = failed
+	for _, node := range nodes {
+		if _, ok := failed[node.Address()]; ok {
+			continue
+		}
+		report.Warmed = append(report.Warmed, node.Address())
+	}
+	for address, warmErr := range failed {
+		f.logger.Warn().
+			String("service", service).
+			String("address", address).
+			Err(warmErr).
+			Log("can't warm up connections")
+	}
+	return
+}
+
+func (f *Factory) warmupNodes(service string) (nodes []discovery.Node, err error) {
+	if lister, ok := f.bln.(NodeLister); ok {
+		return lister.Nodes(service)
+	}
+	// Decline takes a node out of rotation, only Find is safe to call here
+	seen := make(map[string]bool)
+	for i := 0; i < maxWarmupFinds; i++ {
+		node, findErr := f.bln.Find(service)
+		if findErr != nil {
+			if len(nodes) == 0 {
+				err = findErr
+			}
+			return
+		}
+		if seen[node.Address()] {
+			return
+		}
+		seen[node.Address()] = true
+		nodes = append(nodes, node)
+	}
+	return
+}
+
+func (f *Factory) warmConn(address string, deadline time.Time) (err error) {
+	fhc := f.New().(*fastHttpClient)
+	fhc.setAddress(address)
+	fhc.req.Header.SetMethod(fasthttp.MethodOptions)
+	fhc.req.SetRequestURIBytes(fhc.uri.FullURI())
+	defer fasthttp.ReleaseRequest(fhc.req)
+	defer fasthttp.ReleaseURI(fhc.uri)
+	defer fasthttp.ReleaseArgs(fhc.query)
+	client, err := f.transportFor(fhc)
+	if err != nil {
+		return
+	}
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	host := fhc.poolHost()
+	f.pool.update(host, 0, 1)
+	defer f.pool.update(host, 0, -1)
+	return client.DoDeadline(fhc.req, resp, deadline)
+}
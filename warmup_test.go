@@ -0,0 +1,110 @@
+package refasthttp
+
+import (
+	"github.com/remicro/api/cloud/balancer"
+	"github.com/remicro/refasthttp/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"net"
+	"testing"
+	"time"
+)
+
+type findOnlyBalancer struct {
+	balancer.Balancer
+}
+
+func TestFactory_Warmup(t *testing.T) {
+	t.Run("expect connections to every node and failures reported", func(t *testing.T) {
+		handler := func(ctx *fasthttp.RequestCtx) {
+			assert.Equal(t, fasthttp.MethodOptions, string(ctx.Method()))
+			time.Sleep(30 * time.Millisecond)
+		}
+		first := reFastHttpFixture.New(t, handler)
+		defer first.Finish()
+		second := reFastHttpFixture.New(t, handler)
+		defer second.Finish()
+
+		silent, err := net.Listen("tcp4", "localhost:0")
+		require.NoError(t, err)
+		defer silent.Close()
+		go func() {
+			var conns []net.Conn
+			for {
+				conn, err := silent.Accept()
+				if err != nil {
+					break
+				}
+				conns = append(conns, conn)
+			}
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		silentAddress := "http://" + silent.Addr().String()
+
+		bln := reFastHttpFixture.Balancer(map[string][]string{
+			"service": {first.Address(), second.Address(), silentAddress},
+		})
+		factory := NewFactory().Balancer(bln).WarmupBudget(200 * time.Millisecond)
+
+		started := time.Now()
+		report, err := factory.Warmup("service", 2)
+		require.NoError(t, err)
+		assert.True(t, time.Since(started) < time.Second)
+		assert.Equal(t, []string{first.Address(), second.Address()}, report.Warmed)
+		require.Len(t, report.Failed, 1)
+		assert.Error(t, report.Failed[silentAddress])
+
+		stats := factory.Stats()
+		assert.Equal(t, HostStats{Open: 2, Idle: 2}, stats[fixtureHost(first)])
+		assert.Equal(t, HostStats{Open: 2, Idle: 2}, stats[fixtureHost(second)])
+	})
+
+	t.Run("expect warmup to bypass limits and instrumentation", func(t *testing.T) {
+		fx := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {})
+		defer fx.Finish()
+		tracer := &recordTracer{}
+		metrics := &recordMetrics{}
+		factory := NewFactory().
+			Balancer(reFastHttpFixture.Balancer(map[string][]string{"service": {fx.Address()}})).
+			RateLimit("service", RateLimit{Rate: 1, Burst: 1}).
+			Tracing(tracer).
+			Metrics(metrics)
+
+		report, err := factory.Warmup("service", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{fx.Address()}, report.Warmed)
+		assert.Empty(t, tracer.Spans())
+		assert.Empty(t, metrics.results)
+		assert.Equal(t, HostStats{Open: 2, Idle: 2}, factory.Stats()[fixtureHost(fx)])
+
+		_, err = factory.Service("service").GET("/").Go()
+		require.NoError(t, err)
+	})
+
+	t.Run("expect error for unknown service", func(t *testing.T) {
+		factory := NewFactory().Balancer(reFastHttpFixture.Balancer(nil))
+		_, err := factory.Warmup("service", 1)
+		assert.Equal(t, balancer.ErrUnknownService, err)
+	})
+
+	t.Run("expect nodes found one by one when balancer can't list them", func(t *testing.T) {
+		first := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {})
+		defer first.Finish()
+		second := reFastHttpFixture.New(t, func(ctx *fasthttp.RequestCtx) {})
+		defer second.Finish()
+		bln := reFastHttpFixture.Balancer(map[string][]string{"service": {first.Address(), second.Address()}})
+		factory := NewFactory().Balancer(findOnlyBalancer{bln})
+
+		report, err := factory.Warmup("service", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{first.Address(), second.Address()}, report.Warmed)
+		assert.Equal(t, HostStats{Open: 1, Idle: 1}, factory.Stats()[fixtureHost(first)])
+		assert.Equal(t, HostStats{Open: 1, Idle: 1}, factory.Stats()[fixtureHost(second)])
+
+		_, err = NewFactory().Balancer(findOnlyBalancer{reFastHttpFixture.Balancer(nil)}).Warmup("service", 1)
+		assert.Equal(t, balancer.ErrUnknownService, err)
+	})
+}